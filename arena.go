@@ -0,0 +1,395 @@
+package liteLRU
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	arenaGranule    = 32 // smallest block size; every block is 32 << class bytes
+	arenaClasses    = 12 // 32 B .. 64 KiB
+	arenaHeaderSize = 128
+	recordHeader    = 10 // methodLen, pathLen, nParams, nExtra, nameLen (uint16 each)
+	maxRecordField  = 0xFFFF
+)
+
+// arenaHeader is the allocator control block. It lives at the start of the
+// slab itself rather than in the Go heap so that the whole arena, including
+// its free lists, is a single position-independent mapping.
+type arenaHeader struct {
+	bump atomic.Uint64               // next never-allocated granule
+	free [arenaClasses]atomic.Uint64 // Treiber heads: ABA tag<<32 | granule+1
+}
+
+// keyArena is an off-heap slab allocator for entry records. A record holds the
+// method, path and params of one entry; slots refer to records by a packed
+// reference (granule+1)<<8 | class, so the GC never sees the bytes.
+//
+// Blocks are never split or merged: a granule handed out for a class belongs
+// to that class forever. A reader holding a stale reference may therefore see
+// the bytes of a newer record, but never bytes outside a block of the size it
+// expects. Combined with the slot seqlock this makes immediate reuse of
+// evicted blocks memory-safe: readers bounds-check while decoding and discard
+// whatever they read if the seqlock moved.
+type keyArena struct {
+	hdr      *arenaHeader
+	links    []atomic.Uint32 // free-list successor (granule+1) per granule
+	data     []byte
+	granules uint64
+	slab     []byte
+
+	// limbo holds blocks retired while borrowers were active, per epoch bucket
+	// and class, until the epoch domain declares them unreachable. parked
+	// counts them.
+	limbo  [3][arenaClasses]atomic.Uint32
+	parked atomic.Int64
+}
+
+// arenaSize returns the number of granules of an arena of about size bytes
+// and the size of the slab holding it.
+func arenaSize(size int) (granules, total int) {
+	granules = max(size/arenaGranule, 1<<(arenaClasses-1))
+	return granules, arenaHeaderSize + granules*4 + granules*arenaGranule
+}
+
+// newKeyArena carves a header, link table and data region out of one slab.
+func newKeyArena(size int) *keyArena {
+	granules, total := arenaSize(size)
+	slab, raw := mmapSlice[byte](total)
+	a := arenaOver(slab, granules)
+	a.slab = raw
+	return a
+}
+
+// arenaOver lays out an arena of granules granules over slab, which must be
+// zeroed or already hold an arena of the same size, as in a shared mapping.
+func arenaOver(slab []byte, granules int) *keyArena {
+	return &keyArena{
+		hdr:      (*arenaHeader)(unsafe.Pointer(&slab[0])),
+		links:    unsafe.Slice((*atomic.Uint32)(unsafe.Pointer(&slab[arenaHeaderSize])), granules),
+		data:     slab[arenaHeaderSize+granules*4:],
+		granules: uint64(granules),
+	}
+}
+
+// arenaClass returns the smallest class whose block holds size bytes, or -1.
+func arenaClass(size int) int {
+	for class := 0; class < arenaClasses; class++ {
+		if size <= arenaGranule<<class {
+			return class
+		}
+	}
+	return -1
+}
+
+// alloc returns the first granule of a free block of the given class.
+func (a *keyArena) alloc(class int) (uint64, bool) {
+	head := &a.hdr.free[class]
+	for {
+		h := head.Load()
+		g := uint32(h)
+		if g == 0 {
+			break
+		}
+		next := a.links[g-1].Load()
+		if head.CompareAndSwap(h, (h>>32+1)<<32|uint64(next)) {
+			return uint64(g - 1), true
+		}
+	}
+
+	n := uint64(1) << class
+	for {
+		g := a.hdr.bump.Load()
+		if g+n > a.granules {
+			return 0, false
+		}
+		if a.hdr.bump.CompareAndSwap(g, g+n) {
+			return g, true
+		}
+	}
+}
+
+// free pushes the block referenced by ref back onto its class free list.
+func (a *keyArena) free(ref uint64) {
+	if ref == 0 {
+		return
+	}
+	g := uint32(ref>>8) - 1
+	head := &a.hdr.free[ref&0xFF]
+	for {
+		h := head.Load()
+		a.links[g].Store(uint32(h))
+		if head.CompareAndSwap(h, (h>>32+1)<<32|uint64(g+1)) {
+			return
+		}
+	}
+}
+
+// retire parks the block referenced by ref in the limbo list of an epoch
+// bucket instead of freeing it, so borrowers pinned in that epoch can keep
+// reading it.
+func (a *keyArena) retire(ref, epoch uint64) {
+	if ref == 0 {
+		return
+	}
+	g := uint32(ref>>8) - 1
+	head := &a.limbo[epoch%3][ref&0xFF]
+	for {
+		h := head.Load()
+		a.links[g].Store(h)
+		if head.CompareAndSwap(h, g+1) {
+			a.parked.Add(1)
+			return
+		}
+	}
+}
+
+// reclaim moves every block parked in an epoch bucket onto the free lists.
+func (a *keyArena) reclaim(bucket uint64) {
+	for class := range a.limbo[bucket] {
+		for g := a.limbo[bucket][class].Swap(0); g != 0; {
+			next := a.links[g-1].Load()
+			a.free(uint64(g)<<8 | uint64(class))
+			a.parked.Add(-1)
+			g = next
+		}
+	}
+}
+
+// block returns the bytes of the block referenced by ref.
+func (a *keyArena) block(ref uint64) []byte {
+	off := (ref>>8 - 1) * arenaGranule
+	return a.data[off : off+arenaGranule<<(ref&0xFF)]
+}
+
+// put encodes an entry record into a fresh block and returns its reference,
+// or 0 if the record is too large or the arena is exhausted.
+func (a *keyArena) put(method, path string, extra *[MaxKeyExtra]string, name string, params []Param) uint64 {
+	size, ok := recordSize(method, path, extra, name, params)
+	if !ok {
+		return 0
+	}
+	class := arenaClass(size)
+	if class < 0 {
+		return 0
+	}
+	g, ok := a.alloc(class)
+	if !ok {
+		return 0
+	}
+	ref := (g+1)<<8 | uint64(class)
+	encodeRecord(a.block(ref), method, path, extra, name, params)
+	return ref
+}
+
+// An entry record is a header, a table of param key/value lengths, a table of
+// extra key part lengths, and then the method, path, extra parts, handler name
+// and param bytes. The handler name is only used by shared caches. Arena
+// blocks hold one record each, as do the heap records of the compact layout.
+// Decoders bounds-check everything they read, because an arena block may be
+// rewritten under a reader.
+
+// recordSize returns the encoded size of a record, or false if a field is
+// too long to encode.
+func recordSize(method, path string, extra *[MaxKeyExtra]string, name string, params []Param) (int, bool) {
+	if len(method) > maxRecordField || len(path) > maxRecordField || len(name) > maxRecordField ||
+		len(params) > maxRecordField {
+		return 0, false
+	}
+	ne := extraCount(extra)
+	size := recordHeader + 4*len(params) + 2*ne + len(method) + len(path) + len(name)
+	for i := 0; i < ne; i++ {
+		if len(extra[i]) > maxRecordField {
+			return 0, false
+		}
+		size += len(extra[i])
+	}
+	for i := range params {
+		if len(params[i].Key) > maxRecordField || len(params[i].Value) > maxRecordField {
+			return 0, false
+		}
+		size += len(params[i].Key) + len(params[i].Value)
+	}
+	return size, true
+}
+
+// encodeRecord writes a record into b, which must hold recordSize bytes.
+func encodeRecord(b []byte, method, path string, extra *[MaxKeyExtra]string, name string, params []Param) {
+	ne := extraCount(extra)
+	binary.LittleEndian.PutUint16(b[0:], uint16(len(method)))
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(path)))
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(params)))
+	binary.LittleEndian.PutUint16(b[6:], uint16(ne))
+	binary.LittleEndian.PutUint16(b[8:], uint16(len(name)))
+	off := recordHeader
+	for i := range params {
+		binary.LittleEndian.PutUint16(b[off:], uint16(len(params[i].Key)))
+		binary.LittleEndian.PutUint16(b[off+2:], uint16(len(params[i].Value)))
+		off += 4
+	}
+	for i := 0; i < ne; i++ {
+		binary.LittleEndian.PutUint16(b[off:], uint16(len(extra[i])))
+		off += 2
+	}
+	off += copy(b[off:], method)
+	off += copy(b[off:], path)
+	for i := 0; i < ne; i++ {
+		off += copy(b[off:], extra[i])
+	}
+	off += copy(b[off:], name)
+	for i := range params {
+		off += copy(b[off:], params[i].Key)
+		off += copy(b[off:], params[i].Value)
+	}
+}
+
+// keyOffset returns the offset of the method bytes of a record and its
+// number of extra key parts.
+func keyOffset(b []byte) (off, ne int) {
+	ne = int(binary.LittleEndian.Uint16(b[6:]))
+	return recordHeader + 4*int(binary.LittleEndian.Uint16(b[4:])) + 2*ne, ne
+}
+
+// recordMatches reports whether record b has the given key.
+func recordMatches(b []byte, method, path string, extra *[MaxKeyExtra]string) bool {
+	if int(binary.LittleEndian.Uint16(b[0:])) != len(method) ||
+		int(binary.LittleEndian.Uint16(b[2:])) != len(path) {
+		return false
+	}
+	off, ne := keyOffset(b)
+	if ne != extraCount(extra) || off+len(method)+len(path) > len(b) {
+		return false
+	}
+	if string(b[off:off+len(method)]) != method ||
+		string(b[off+len(method):off+len(method)+len(path)]) != path {
+		return false
+	}
+	lens := off - 2*ne
+	off += len(method) + len(path)
+	for i := 0; i < ne; i++ {
+		n := int(binary.LittleEndian.Uint16(b[lens+2*i:]))
+		if n != len(extra[i]) || off+n > len(b) || string(b[off:off+n]) != extra[i] {
+			return false
+		}
+		off += n
+	}
+	return true
+}
+
+// recordKey returns the method and path of record b, aliasing b.
+func recordKey(b []byte) (method, path string, ok bool) {
+	ml := int(binary.LittleEndian.Uint16(b[0:]))
+	pl := int(binary.LittleEndian.Uint16(b[2:]))
+	off, _ := keyOffset(b)
+	if off+ml+pl > len(b) {
+		return "", "", false
+	}
+	return arenaString(b, off, ml), arenaString(b, off+ml, pl), true
+}
+
+// recordExtra copies the extra key parts of record b into dst, aliasing b.
+func recordExtra(b []byte, dst *[MaxKeyExtra]string) bool {
+	off, ne := keyOffset(b)
+	if ne > MaxKeyExtra || off > len(b) {
+		return false
+	}
+	lens := off - 2*ne
+	off += int(binary.LittleEndian.Uint16(b[0:])) + int(binary.LittleEndian.Uint16(b[2:]))
+	for i := 0; i < ne; i++ {
+		n := int(binary.LittleEndian.Uint16(b[lens+2*i:]))
+		if off+n > len(b) {
+			return false
+		}
+		dst[i] = arenaString(b, off, n)
+		off += n
+	}
+	return true
+}
+
+// nameOffset returns the offset of the handler name of record b, which
+// follows the key.
+func nameOffset(b []byte) (int, bool) {
+	off, ne := keyOffset(b)
+	if off > len(b) {
+		return 0, false
+	}
+	lens := off - 2*ne
+	for i := 0; i < ne; i++ {
+		off += int(binary.LittleEndian.Uint16(b[lens+2*i:]))
+	}
+	return off + int(binary.LittleEndian.Uint16(b[0:])) + int(binary.LittleEndian.Uint16(b[2:])), true
+}
+
+// recordName returns the handler name of record b, aliasing b.
+func recordName(b []byte) (string, bool) {
+	off, ok := nameOffset(b)
+	n := int(binary.LittleEndian.Uint16(b[8:]))
+	if !ok || off+n > len(b) {
+		return "", false
+	}
+	return arenaString(b, off, n), true
+}
+
+// recordParams decodes the params of record b into dst (or a fresh slice if
+// dst is too small). The returned strings alias b.
+func recordParams(b []byte, dst []Param) (out []Param, ok bool) {
+	n := int(binary.LittleEndian.Uint16(b[4:]))
+	if n == 0 {
+		return nil, true
+	}
+	off, ok := nameOffset(b)
+	if !ok {
+		return nil, false
+	}
+	off += int(binary.LittleEndian.Uint16(b[8:]))
+
+	if cap(dst) >= n {
+		out = dst[:n]
+	} else {
+		out = make([]Param, n)
+	}
+	for i := 0; i < n; i++ {
+		kl := int(binary.LittleEndian.Uint16(b[recordHeader+4*i:]))
+		vl := int(binary.LittleEndian.Uint16(b[recordHeader+4*i+2:]))
+		if off+kl+vl > len(b) {
+			return nil, false
+		}
+		out[i] = Param{Key: arenaString(b, off, kl), Value: arenaString(b, off+kl, vl)}
+		off += kl + vl
+	}
+	return out, true
+}
+
+// detachParams re-points the strings of params, decoded from a record that
+// may be rewritten, at a private copy of their bytes made with a single
+// allocation, so they can be handed to callers that keep them.
+func detachParams(params []Param) {
+	n := 0
+	for i := range params {
+		n += len(params[i].Key) + len(params[i].Value)
+	}
+	if n == 0 {
+		return
+	}
+	buf := make([]byte, 0, n)
+	for i := range params {
+		buf = append(buf, params[i].Key...)
+		buf = append(buf, params[i].Value...)
+	}
+	s := unsafe.String(unsafe.SliceData(buf), n)
+	off := 0
+	for i := range params {
+		kl, vl := len(params[i].Key), len(params[i].Value)
+		params[i] = Param{Key: s[off : off+kl], Value: s[off+kl : off+kl+vl]}
+		off += kl + vl
+	}
+}
+
+// arenaString returns b[off:off+n] as a string without copying.
+func arenaString(b []byte, off, n int) string {
+	if n == 0 {
+		return ""
+	}
+	return unsafe.String(&b[off], n)
+}
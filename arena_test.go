@@ -0,0 +1,134 @@
+package liteLRU
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestArenaAddGet(t *testing.T) {
+	c := NewLRUCache(128, 10, WithArena(0))
+	defer c.Close()
+
+	if c.methods != nil || c.paths != nil || c.params != nil {
+		t.Fatal("arena mode should not allocate heap key/param arrays")
+	}
+
+	called := false
+	c.Add("GET", "/users/42", func() { called = true }, []Param{{Key: "id", Value: "42"}, {Key: "fmt", Value: ""}})
+
+	var buf [4]Param
+	h, params, ok := c.Get("GET", "/users/42", buf[:0])
+	if !ok {
+		t.Fatal("expected hit")
+	}
+	h()
+	if !called {
+		t.Fatal("handler not stored")
+	}
+	if len(params) != 2 || params[0] != (Param{"id", "42"}) || params[1] != (Param{"fmt", ""}) {
+		t.Fatalf("unexpected params %+v", params)
+	}
+	if _, _, ok := c.Get("POST", "/users/42", nil); ok {
+		t.Fatal("method must be part of the key")
+	}
+
+	c.Add("GET", "/users/42", nil, []Param{{Key: "id", Value: strings.Repeat("x", 300)}})
+	_, params, ok = c.Get("GET", "/users/42", nil)
+	if !ok || len(params) != 1 || len(params[0].Value) != 300 {
+		t.Fatalf("update not visible: %v %+v", ok, params)
+	}
+}
+
+func TestArenaReclaimsEvictedBlocks(t *testing.T) {
+	// A minimal arena only fits a few thousand small records; churning far
+	// more keys than that through it must keep working via the free lists.
+	c := NewLRUCache(64, 10, WithArena(1))
+	defer c.Close()
+
+	for i := 0; i < 100000; i++ {
+		c.Add("GET", fmt.Sprintf("/churn/%d", i), nil, []Param{{Key: "i", Value: fmt.Sprint(i)}})
+	}
+	_, _, drops, _ := c.Stats()
+	if drops != 0 {
+		t.Fatalf("arena leaked blocks: %d drops", drops)
+	}
+	_, params, ok := c.Get("GET", "/churn/99999", nil)
+	if !ok || params[0].Value != "99999" {
+		t.Fatalf("latest entry missing: %v %+v", ok, params)
+	}
+}
+
+func TestArenaOversizedEntryDropped(t *testing.T) {
+	c := NewLRUCache(64, 10, WithArena(0))
+	defer c.Close()
+
+	c.Add("GET", "/big", nil, []Param{{Key: "v", Value: strings.Repeat("x", 70000)}})
+	if _, _, ok := c.Get("GET", "/big", nil); ok {
+		t.Fatal("oversized record should not be cached")
+	}
+	if _, _, drops, _ := c.Stats(); drops != 1 {
+		t.Fatalf("expected 1 drop, got %d", drops)
+	}
+}
+
+func TestArenaConcurrent(t *testing.T) {
+	c := NewLRUCache(256, 10, WithArena(0))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var buf [2]Param
+			for i := 0; i < 20000; i++ {
+				path := fmt.Sprintf("/r/%d", (i*7+w)%1000)
+				if i%4 == 0 {
+					c.Add("GET", path, nil, []Param{{Key: "path", Value: path}})
+					continue
+				}
+				// Returned strings alias arena blocks that concurrent writers may
+				// recycle, so only the read path itself is exercised here.
+				c.Get("GET", path, buf[:0])
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for i := 0; i < 1000; i++ {
+		path := fmt.Sprintf("/r/%d", i)
+		if _, params, ok := c.Get("GET", path, nil); ok {
+			if len(params) != 1 || params[0].Value != path {
+				t.Fatalf("corrupt entry for %s: %+v", path, params)
+			}
+		}
+	}
+}
+
+func TestArenaParamsOutliveEntry(t *testing.T) {
+	c := NewLRUCache(64, 10, WithArena(1))
+	defer c.Close()
+
+	c.Add("GET", "/a", nil, []Param{{Key: "k", Value: "first"}})
+	_, got, ok := c.Get("GET", "/a", nil)
+	if !ok {
+		t.Fatal("expected hit")
+	}
+	_, peeked, _ := c.Peek("GET", "/a", nil)
+	_, _, any, _ := c.GetAnyMethod("/a", nil)
+
+	// The freed block is reused at once by a record of the same size class.
+	c.Remove("GET", "/a")
+	c.Add("GET", "/a", nil, []Param{{Key: "k", Value: "XXXXX"}})
+
+	for _, params := range [][]Param{got, peeked, any} {
+		if len(params) != 1 || params[0] != (Param{"k", "first"}) {
+			t.Fatalf("returned params changed after the entry was replaced: %+v", params)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { c.Get("GET", "/a", got[:0]) }); allocs != 1 {
+		t.Fatalf("Get allocated %v times, want 1", allocs)
+	}
+}
@@ -44,6 +44,19 @@ func main() {
 		lCache.Add("GET", key, nil, nil)
 	})
 
+	// 1b. Associativity: the same workload with each supported set size
+	for _, ways := range []int{16, 32, 64, 128} {
+		fmt.Printf("\n--- liteLRU (%d-way sets) ---\n", ways)
+		wCache := liteLRU.NewLRUCache(capacity, 10, liteLRU.WithWays(ways))
+		runBench(ops, numWorkers, func(key string) {
+			wCache.Get("GET", key, nil)
+		}, func(key string) {
+			wCache.Add("GET", key, nil, nil)
+		})
+		_, _, _, ratio := wCache.Stats()
+		fmt.Printf("Hit Rate  : %.2f%%\n", ratio*100)
+	}
+
 	// 2. Benchmark NoPad
 	fmt.Println("\n--- liteLRU (No Padding) ---")
 	npCache := nopad.NewLRUCache(capacity, 10)
@@ -206,7 +206,8 @@ func main() {
 
 		if *cacheType == "litelru" {
 			var pbuf [4]liteLRU.Param
-			if handler, params, ok := lite.Get("GET", path, pbuf[:0]); ok {
+			switch handler, params, status, res := lite.Lookup("GET", path, pbuf[:0]); res {
+			case liteLRU.Hit:
 				if handler != nil {
 					handler()
 				}
@@ -215,6 +216,9 @@ func main() {
 					w.Write([]byte(params[0].Value))
 				}
 				return
+			case liteLRU.NegativeHit:
+				w.WriteHeader(status)
+				return
 			}
 			
 			parts := strings.Split(path, "/")
@@ -232,6 +236,7 @@ func main() {
 				w.Write([]byte(idStr))
 				return
 			}
+			lite.AddNotFound("GET", path, http.StatusNotFound)
 			http.NotFound(w, r)
 			return
 		}
@@ -32,39 +32,10 @@ func main() {
 	for _, cap := range capacities {
 		fmt.Printf("\n=== Cache Capacity: %d (%.0f%% of working set) ===\n", cap, float64(cap)/float64(workingSetSize)*100)
 
-		// 1. liteLRU
-		lite := liteLRU.NewLRUCache(cap, 5)
-		var liteHits, liteMisses atomic.Uint64
-
-		// Warmup liteLRU
-		for i := 0; i < warmupOps; i++ {
-			key := ops[i]
-			if _, _, ok := lite.Get("GET", key, nil); !ok {
-				lite.Add("GET", key, nil, nil)
-			}
-		}
-
-		// Measured Phase
-		var wg sync.WaitGroup
-		wg.Add(8)
-		chunkSize := (numOps - warmupOps) / 8
-		for i := 0; i < 8; i++ {
-			go func(start, end int) {
-				for j := start; j < end; j++ {
-					key := ops[j]
-					if _, _, ok := lite.Get("GET", key, nil); ok {
-						liteHits.Add(1)
-					} else {
-						liteMisses.Add(1)
-						lite.Add("GET", key, nil, nil)
-					}
-				}
-				wg.Done()
-			}(warmupOps+(i*chunkSize), warmupOps+((i+1)*chunkSize))
-		}
-		wg.Wait()
+		// 1. liteLRU, with one and with two candidate sets per key
 		measuredOps := numOps - warmupOps
-		fmt.Printf("liteLRU   Hit Rate: %.2f%%\n", float64(liteHits.Load())/float64(measuredOps)*100)
+		fmt.Printf("liteLRU   Hit Rate: %.2f%%\n", liteHitRate(cap, ops)*100)
+		fmt.Printf("liteLRU2C Hit Rate: %.2f%%\n", liteHitRate(cap, ops, liteLRU.WithTwoChoice())*100)
 
 		// 2. Otter
 		otterCache, err := otter.MustBuilder[string, any](cap).
@@ -74,6 +45,8 @@ func main() {
 			panic(err)
 		}
 		var otterHits, otterMisses atomic.Uint64
+		var wg sync.WaitGroup
+		chunkSize := (numOps - warmupOps) / 8
 
 		// Warmup Otter
 		for i := 0; i < warmupOps; i++ {
@@ -102,3 +75,38 @@ func main() {
 		fmt.Printf("Otter Hit Rate: %.2f%%\n", float64(otterHits.Load())/float64(measuredOps)*100)
 	}
 }
+
+// liteHitRate replays ops against a liteLRU cache built with opts: a
+// single-threaded warmup followed by a measured phase on 8 goroutines.
+func liteHitRate(capacity int, ops []string, opts ...liteLRU.Option) float64 {
+	lite := liteLRU.NewLRUCache(capacity, 5, opts...)
+	defer lite.Close()
+	var liteHits, liteMisses atomic.Uint64
+
+	for i := 0; i < warmupOps; i++ {
+		key := ops[i]
+		if _, _, ok := lite.Get("GET", key, nil); !ok {
+			lite.Add("GET", key, nil, nil)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(8)
+	chunkSize := (numOps - warmupOps) / 8
+	for i := 0; i < 8; i++ {
+		go func(start, end int) {
+			for j := start; j < end; j++ {
+				key := ops[j]
+				if _, _, ok := lite.Get("GET", key, nil); ok {
+					liteHits.Add(1)
+				} else {
+					liteMisses.Add(1)
+					lite.Add("GET", key, nil, nil)
+				}
+			}
+			wg.Done()
+		}(warmupOps+(i*chunkSize), warmupOps+((i+1)*chunkSize))
+	}
+	wg.Wait()
+	return float64(liteHits.Load()) / float64(numOps-warmupOps)
+}
@@ -0,0 +1,93 @@
+package liteLRU
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Plausible coherence-line sizes; sysfs values outside this range are ignored.
+const (
+	minLineSize = 32
+	maxLineSize = 512
+)
+
+// WithCacheLineSize overrides the coherence-line size that slot seqlocks and
+// statistics stripes are padded to. n is rounded up to a power of two and
+// clamped to 32..512; values <= 0 select the size detected at run time, which
+// is the default. With WithCompactLayout seqlocks are packed regardless and
+// only the statistics stripes are padded.
+func WithCacheLineSize(n int) Option {
+	return func(cfg *config) {
+		if n <= 0 {
+			cfg.lineSize = 0
+			return
+		}
+		cfg.lineSize = min(max(nextPowerOfTwo(n), minLineSize), maxLineSize)
+	}
+}
+
+// hostLineSize is the coherence-line size of the machine: the largest line
+// size sysfs reports for any cache level of CPU 0 on Linux, or the per
+// architecture CacheLineSize where that is unavailable. It is read once per
+// process.
+var hostLineSize = sync.OnceValue(func() int {
+	if n := detectLineSize(sysCacheFS()); n != 0 {
+		return n
+	}
+	return CacheLineSize
+})
+
+// detectLineSize returns the largest coherency_line_size listed under the
+// index* directories of fsys, a sysfs CPU cache directory, or 0 if none can
+// be read or none is a plausible power of two.
+func detectLineSize(fsys fs.FS) int {
+	if fsys == nil {
+		return 0
+	}
+	names, _ := fs.Glob(fsys, "index*/coherency_line_size")
+	size := 0
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil || n < minLineSize || n > maxLineSize || n&(n-1) != 0 {
+			continue
+		}
+		size = max(size, n)
+	}
+	return size
+}
+
+// padTo rounds size up to a multiple of line, a power of two.
+func padTo(size, line uintptr) uintptr {
+	return (size + line - 1) &^ (line - 1)
+}
+
+// statStripes holds statStripes a runtime stride apart, so that each one has
+// its own cache line whatever the line size of the machine.
+type statStripes struct {
+	mem    []byte
+	stride uintptr
+}
+
+func newStatStripes(n int, line uintptr) statStripes {
+	stride := padTo(unsafe.Sizeof(statStripe{}), line)
+	return statStripes{mem: make([]byte, uintptr(n)*stride), stride: stride}
+}
+
+// at returns stripe i, which must be below the number of stripes.
+func (s *statStripes) at(i uint64) *statStripe {
+	return (*statStripe)(unsafe.Add(unsafe.Pointer(unsafe.SliceData(s.mem)), uintptr(i)*s.stride))
+}
+
+// LineSize returns the coherence-line size the cache pads its seqlocks and
+// statistics stripes to, as detected at construction or set by
+// WithCacheLineSize.
+func (c *LRUCache) LineSize() int {
+	return int(c.line)
+}
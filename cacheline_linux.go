@@ -0,0 +1,13 @@
+//go:build linux
+
+package liteLRU
+
+import (
+	"io/fs"
+	"os"
+)
+
+// sysCacheFS returns the sysfs directory describing the caches of CPU 0.
+func sysCacheFS() fs.FS {
+	return os.DirFS("/sys/devices/system/cpu/cpu0/cache")
+}
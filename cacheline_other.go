@@ -0,0 +1,11 @@
+//go:build !linux
+
+package liteLRU
+
+import "io/fs"
+
+// sysCacheFS returns nil: line sizes are only detected on Linux, elsewhere
+// the per-architecture CacheLineSize applies.
+func sysCacheFS() fs.FS {
+	return nil
+}
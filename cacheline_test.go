@@ -0,0 +1,87 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectLineSize(t *testing.T) {
+	line := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	for _, tc := range []struct {
+		name string
+		fsys fstest.MapFS
+		want int
+	}{
+		{"x86", fstest.MapFS{
+			"index0/coherency_line_size": line("64\n"),
+			"index1/coherency_line_size": line("64\n"),
+			"index2/coherency_line_size": line("64\n"),
+		}, 64},
+		// The largest level wins, as on parts whose outer caches use
+		// 128-byte lines.
+		{"mixed", fstest.MapFS{
+			"index0/coherency_line_size": line("64\n"),
+			"index2/coherency_line_size": line("128\n"),
+		}, 128},
+		{"garbage", fstest.MapFS{
+			"index0/coherency_line_size": line("0\n"),
+			"index1/coherency_line_size": line("96\n"),
+			"index2/coherency_line_size": line("4096\n"),
+			"index3/coherency_line_size": line("lots\n"),
+		}, 0},
+		{"empty", fstest.MapFS{}, 0},
+	} {
+		if got := detectLineSize(tc.fsys); got != tc.want {
+			t.Errorf("%s: detected %d, want %d", tc.name, got, tc.want)
+		}
+	}
+	if got := detectLineSize(nil); got != 0 {
+		t.Errorf("nil fs: detected %d", got)
+	}
+}
+
+func TestCacheLineSizeOption(t *testing.T) {
+	c := NewLRUCache(128, 10)
+	if c.LineSize() != hostLineSize() {
+		t.Fatalf("default line %d, host %d", c.LineSize(), hostLineSize())
+	}
+	c.Close()
+
+	for _, tc := range []struct{ in, want int }{{0, hostLineSize()}, {16, 32}, {100, 128}, {256, 256}, {4096, 512}} {
+		c := NewLRUCache(128, 10, WithCacheLineSize(tc.in))
+		if c.LineSize() != tc.want {
+			t.Errorf("WithCacheLineSize(%d) gave %d", tc.in, c.LineSize())
+		}
+		// Seqlocks take at least a full line each; 32-byte lines need two.
+		if c.stride%c.line != 0 || c.stride < 48 || c.stats.stride%c.line != 0 {
+			t.Errorf("%d-byte lines: slot stride %d, stat stride %d", c.line, c.stride, c.stats.stride)
+		}
+		if len(c.states) != 128*int(c.stride) {
+			t.Errorf("%d-byte lines: %d state bytes", c.line, len(c.states))
+		}
+		c.Close()
+	}
+}
+
+func TestCacheLineSizeStrided(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithCacheLineSize(256))
+	defer c.Close()
+
+	ns := c.Namespace("t", 0)
+	for i := 0; i < 1024; i++ {
+		p := fmt.Sprintf("/s/%d", i)
+		c.Add("GET", p, nil, []Param{{Key: "p", Value: p}})
+		ns.Add("GET", p, nil, nil)
+		if _, params, ok := c.Get("GET", p, nil); !ok || params[0].Value != p {
+			t.Fatalf("%s: %v %+v", p, ok, params)
+		}
+		ns.Get("GET", p, nil)
+	}
+	if hits, _, _, _ := c.Stats(); hits != 2048 {
+		t.Fatalf("cache counted %d hits", hits)
+	}
+	if hits, _, _, _ := ns.Stats(); hits != 1024 {
+		t.Fatalf("namespace counted %d hits", hits)
+	}
+}
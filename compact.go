@@ -0,0 +1,68 @@
+package liteLRU
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// WithCompactLayout trades some false sharing for a smaller per-entry
+// footprint, for memory-constrained deployments such as sidecars.
+//
+// Slot seqlocks and metadata are packed back to back, 40 bytes per slot,
+// instead of each filling a cache line, so writers of neighbouring slots
+// contend for the same line. The heap arrays of methods, paths, params and
+// extra key parts are replaced by a single pointer per slot to an immutable
+// record holding all of them in the arena encoding, allocated once per write.
+// Together with WithArena only the seqlocks are packed, 48 bytes apart, as
+// they then also hold the arena reference.
+//
+// On 64-bit platforms this cuts the fixed cost of a slot, counting its
+// seqlock, chunk share, handler and key and param references, from 132 bytes
+// (196 with 128-byte cache lines) to 60, a factor of 2.2 (3.3), plus the
+// record of each entry. Param strings returned by Get alias the record, which
+// never changes once written.
+func WithCompactLayout() Option {
+	return func(cfg *config) {
+		cfg.compact = true
+	}
+}
+
+// newRecord encodes an entry into a heap record for the compact layout: a
+// 4-byte length followed by the record, in one allocation. It returns nil if
+// a field is too long to encode.
+func newRecord(method, path string, extra *[MaxKeyExtra]string, params []Param) *byte {
+	size, ok := recordSize(method, path, extra, "", params)
+	if !ok {
+		return nil
+	}
+	b := make([]byte, 4+size)
+	binary.LittleEndian.PutUint32(b, uint32(size))
+	encodeRecord(b[4:], method, path, extra, "", params)
+	return &b[0]
+}
+
+// record returns the encoded entry of slot idx in arena or compact mode, or
+// nil if the slot holds none. An arena block may be rewritten under the
+// caller; a compact record is immutable. Either way callers validate what
+// they decode with the slot seqlock.
+func (c *LRUCache) record(idx uint32) []byte {
+	if c.arena != nil {
+		ref := c.state(idx).ref.Load()
+		if ref == 0 {
+			return nil
+		}
+		return c.arena.block(ref)
+	}
+	p := c.records[idx].Load()
+	if p == nil {
+		return nil
+	}
+	n := binary.LittleEndian.Uint32(unsafe.Slice(p, 4))
+	return unsafe.Slice(p, 4+n)[4:]
+}
+
+// state returns the seqlock and metadata of slot idx. Slots are stride bytes
+// apart: a cache line each by default, densely packed with WithCompactLayout.
+func (c *LRUCache) state(idx uint32) *slotState {
+	return (*slotState)(unsafe.Add(unsafe.Pointer(unsafe.SliceData(c.states)), uintptr(idx)*c.stride))
+}
@@ -0,0 +1,221 @@
+package liteLRU
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestCompactLayoutGeometry(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithCompactLayout())
+	defer c.Close()
+
+	if c.methods != nil || c.paths != nil || c.params != nil || c.records == nil {
+		t.Fatal("compact mode should keep one record pointer per slot")
+	}
+	if c.stride != 40 || len(c.states) < 1024*int(c.stride) {
+		t.Fatalf("states stride %d, %d bytes", c.stride, len(c.states))
+	}
+	// The last slot must be addressable as a whole slotState.
+	if last := uintptr(unsafe.Pointer(c.state(1023))) + unsafe.Sizeof(slotState{}); last > uintptr(unsafe.Pointer(unsafe.SliceData(c.states)))+uintptr(len(c.states)) {
+		t.Fatal("last slot extends past the states slab")
+	}
+
+	// The fixed cost per slot is 60 bytes against 132 with 64-byte lines and
+	// 196 with 128-byte lines, as documented on WithCompactLayout.
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("slot costs are documented for 64-bit platforms")
+	}
+	for _, line := range []int{64, 128} {
+		d := NewLRUCache(1024, 10, WithCacheLineSize(line))
+		if d.stride != d.line {
+			t.Fatalf("default stride %d, want the %d-byte line", d.stride, d.line)
+		}
+		n, m := slotBytes(c), slotBytes(d)
+		if n != 60 || m != uintptr(line)+68 {
+			t.Fatalf("%d-byte lines: compact slot costs %d bytes, default %d", line, n, m)
+		}
+		if n*2 > m {
+			t.Fatalf("%d-byte lines: compact saves only %.2fx", line, float64(m)/float64(n))
+		}
+		d.Close()
+	}
+
+	a := NewLRUCache(1024, 10, WithCompactLayout(), WithArena(0))
+	defer a.Close()
+	if a.stride != unsafe.Sizeof(slotState{}) {
+		t.Fatalf("compact arena stride %d, want %d", a.stride, unsafe.Sizeof(slotState{}))
+	}
+}
+
+// slotBytes returns the fixed memory cost of one slot, excluding entry data.
+func slotBytes(c *LRUCache) uintptr {
+	n := c.stride + unsafe.Sizeof(chunk{})/64 + unsafe.Sizeof(atomicHandler{})
+	if c.methods != nil {
+		n += 2*unsafe.Sizeof(atomicString{}) + unsafe.Sizeof(atomicSlice{})
+	}
+	if c.records != nil {
+		n += unsafe.Sizeof(c.records[0])
+	}
+	return n
+}
+
+func TestCompactLayoutAddGet(t *testing.T) {
+	for _, opts := range [][]Option{{WithCompactLayout()}, {WithCompactLayout(), WithArena(0)}} {
+		c := NewLRUCache(1024, 10, opts...)
+
+		called := false
+		c.Add("GET", "/users/42", func() { called = true }, []Param{{Key: "id", Value: "42"}, {Key: "fmt", Value: ""}})
+		var buf [4]Param
+		h, params, ok := c.Get("GET", "/users/42", buf[:0])
+		if !ok || len(params) != 2 || params[0] != (Param{"id", "42"}) || params[1] != (Param{"fmt", ""}) {
+			t.Fatalf("Get = %v %+v", ok, params)
+		}
+		h()
+		if !called {
+			t.Fatal("handler not stored")
+		}
+		if _, _, ok := c.Get("POST", "/users/42", nil); ok {
+			t.Fatal("method must be part of the key")
+		}
+
+		c.Add("POST", "/users/42", nil, nil)
+		if m := c.Methods("/users/42"); len(m) != 2 {
+			t.Fatalf("Methods = %v", m)
+		}
+		if !c.View("GET", "/users/42", func(_ HandlerFunc, p []Param) {
+			if len(p) != 2 || p[0].Value != "42" {
+				t.Fatalf("View params %+v", p)
+			}
+		}) {
+			t.Fatal("View missed")
+		}
+		n := 0
+		c.Range(func(method, path string, _ any) bool {
+			if path != "/users/42" {
+				t.Fatalf("Range saw %s %s", method, path)
+			}
+			n++
+			return true
+		})
+		if n != 2 {
+			t.Fatalf("Range visited %d entries", n)
+		}
+		if !c.Remove("POST", "/users/42") {
+			t.Fatal("Remove missed")
+		}
+		if _, _, ok := c.Get("POST", "/users/42", nil); ok {
+			t.Fatal("removed entry still visible")
+		}
+		c.Clear()
+		if c.Len() != 0 {
+			t.Fatalf("Len after Clear = %d", c.Len())
+		}
+		c.Close()
+	}
+}
+
+func TestCompactLayoutParamsOutliveEntry(t *testing.T) {
+	c := NewLRUCache(64, 10, WithCompactLayout())
+	defer c.Close()
+
+	c.Add("GET", "/a", nil, []Param{{Key: "v", Value: "first"}})
+	_, params, _ := c.Get("GET", "/a", nil)
+	c.Add("GET", "/a", nil, []Param{{Key: "v", Value: "other"}})
+	c.Remove("GET", "/a")
+	if params[0].Value != "first" {
+		t.Fatalf("returned params changed to %+v", params)
+	}
+}
+
+func TestCompactLayoutOversizedEntryDropped(t *testing.T) {
+	c := NewLRUCache(64, 10, WithCompactLayout())
+	defer c.Close()
+
+	c.Add("GET", "/"+strings.Repeat("x", maxRecordField+1), nil, nil)
+	if _, _, drops, _ := c.Stats(); drops != 1 || c.Len() != 0 {
+		t.Fatalf("drops = %d, Len = %d", drops, c.Len())
+	}
+}
+
+func TestCompactLayoutVictimBuffer(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithCompactLayout(), WithVictimBuffer(64))
+	defer c.Close()
+
+	paths := collidingPaths(c, 80)
+	for _, p := range paths {
+		c.Add("GET", p, nil, []Param{{Key: "p", Value: p}})
+	}
+	for _, p := range paths {
+		if _, params, ok := c.Get("GET", p, nil); !ok || params[0].Value != p {
+			t.Fatalf("%s: %v %+v", p, ok, params)
+		}
+	}
+	if _, hits := c.VictimStats(); hits == 0 {
+		t.Fatal("no lookups served from the victim buffer")
+	}
+}
+
+// Compact records are immutable, so unlike the default layout concurrent
+// rewrites of the same entries are race-free in heap mode too.
+func TestCompactLayoutConcurrent(t *testing.T) {
+	c := NewLRUCache(256, 10, WithCompactLayout())
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var buf [2]Param
+			for i := 0; i < 20000; i++ {
+				path := fmt.Sprintf("/r/%d", (i*7+w)%1000)
+				if i%4 == 0 {
+					c.Add("GET", path, nil, []Param{{Key: "path", Value: path}})
+					continue
+				}
+				if _, params, ok := c.Get("GET", path, buf[:0]); ok && (len(params) != 1 || params[0].Value != path) {
+					t.Errorf("corrupt entry for %s: %+v", path, params)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// BenchmarkCompactLayout compares Get hits and Add updates in the default
+// and compact layouts and reports the fixed bytes per slot.
+func BenchmarkCompactLayout(b *testing.B) {
+	for _, layout := range []struct {
+		name string
+		opts []Option
+	}{{"default", nil}, {"compact", []Option{WithCompactLayout()}}} {
+		c := NewLRUCache(4096, 10, layout.opts...)
+		paths := make([]string, 1024)
+		for i := range paths {
+			paths[i] = fmt.Sprintf("/api/resource/%d", i)
+			c.Add("GET", paths[i], nil, []Param{{Key: "id", Value: fmt.Sprint(i)}})
+		}
+		b.Run(layout.name+"/get", func(b *testing.B) {
+			var buf [2]Param
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.Get("GET", paths[i&1023], buf[:0])
+					i++
+				}
+			})
+			b.ReportMetric(float64(slotBytes(c)), "B/slot")
+		})
+		b.Run(layout.name+"/add", func(b *testing.B) {
+			params := []Param{{Key: "id", Value: "x"}}
+			for i := 0; i < b.N; i++ {
+				c.Add("GET", paths[i&1023], nil, params)
+			}
+		})
+		c.Close()
+	}
+}
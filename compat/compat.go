@@ -0,0 +1,204 @@
+// Package compat offers the method set of hashicorp/golang-lru on top of the
+// liteLRU chunk engine, so code written against that interface can switch
+// caches without touching its call sites.
+//
+// The engine is set-associative rather than a single list, which shows in a
+// few places: the size is rounded up to a power of two of at least 64, an Add
+// may evict an entry before the cache is full when its 64-slot set is, and
+// Keys returns keys in no particular order.
+package compat
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+// keyMethod is the method part of every key the compat layer stores.
+const keyMethod = "compat"
+
+// entry is the value stored per key: the original key, so that keys can be
+// recovered and colliding key strings detected, and the value.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Cache is a fixed-size concurrent cache with the golang-lru method set. All
+// methods are safe for concurrent use and lock-free, except Resize.
+type Cache[K comparable, V any] struct {
+	cur  atomic.Pointer[liteLRU.LRUCache]
+	opts []liteLRU.Option
+	mu   sync.Mutex // serialises Resize
+}
+
+// New creates a cache holding at least size entries. opts are passed on to
+// liteLRU.NewLRUCache.
+func New[K comparable, V any](size int, opts ...liteLRU.Option) (*Cache[K, V], error) {
+	if size <= 0 {
+		return nil, errors.New("compat: must provide a positive size")
+	}
+	c := &Cache[K, V]{opts: append([]liteLRU.Option{liteLRU.WithValues()}, opts...)}
+	c.cur.Store(newEngine(size, c.opts))
+	return c, nil
+}
+
+// newEngine creates an engine that is closed once the garbage collector finds
+// it unreachable, which is only after every operation using it has returned.
+func newEngine(size int, opts []liteLRU.Option) *liteLRU.LRUCache {
+	lc := liteLRU.NewLRUCache(size, 1, opts...)
+	runtime.SetFinalizer(lc, (*liteLRU.LRUCache).Close)
+	return lc
+}
+
+// Add adds a value to the cache and reports whether an eviction occurred.
+func (c *Cache[K, V]) Add(key K, value V) (evicted bool) {
+	lc := c.cur.Load()
+	evicted = lc.AddValue(keyMethod, keyString(key), &entry[K, V]{key: key, value: value})
+	runtime.KeepAlive(lc)
+	return evicted
+}
+
+// Get looks up a key's value and marks it as recently used.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	lc := c.cur.Load()
+	v, ok := lc.GetValue(keyMethod, keyString(key))
+	runtime.KeepAlive(lc)
+	return unwrap[K, V](v, ok, key)
+}
+
+// Contains reports whether key is in the cache without marking it as
+// recently used.
+func (c *Cache[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Peek returns key's value without marking it as recently used.
+func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
+	lc := c.cur.Load()
+	v, ok := lc.PeekValue(keyMethod, keyString(key))
+	runtime.KeepAlive(lc)
+	return unwrap[K, V](v, ok, key)
+}
+
+// Remove removes key from the cache and reports whether it was present.
+func (c *Cache[K, V]) Remove(key K) (present bool) {
+	lc := c.cur.Load()
+	k := keyString(key)
+	// Only remove the slot if it holds this key, not another one formatted alike.
+	if v, ok := lc.PeekValue(keyMethod, k); ok {
+		if _, ok = unwrap[K, V](v, ok, key); ok {
+			present = lc.Remove(keyMethod, k)
+		}
+	}
+	runtime.KeepAlive(lc)
+	return present
+}
+
+// Keys returns the keys in the cache, in no particular order.
+func (c *Cache[K, V]) Keys() []K {
+	var keys []K
+	c.each(func(e *entry[K, V]) { keys = append(keys, e.key) })
+	return keys
+}
+
+// Values returns the values in the cache, in the same order as Keys would.
+func (c *Cache[K, V]) Values() []V {
+	var values []V
+	c.each(func(e *entry[K, V]) { values = append(values, e.value) })
+	return values
+}
+
+// each calls fn for every entry of the current engine.
+func (c *Cache[K, V]) each(fn func(e *entry[K, V])) {
+	lc := c.cur.Load()
+	lc.Range(func(method, _ string, value any) bool {
+		if e, ok := value.(*entry[K, V]); ok && method == keyMethod {
+			fn(e)
+		}
+		return true
+	})
+	runtime.KeepAlive(lc)
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache[K, V]) Len() int {
+	lc := c.cur.Load()
+	n := lc.Len()
+	runtime.KeepAlive(lc)
+	return n
+}
+
+// Purge removes all entries from the cache.
+func (c *Cache[K, V]) Purge() {
+	lc := c.cur.Load()
+	lc.Clear()
+	runtime.KeepAlive(lc)
+}
+
+// Resize changes the cache size and returns the number of entries that did
+// not fit. Entries are copied into a new engine of the requested size, so
+// values added concurrently with a Resize may be lost.
+func (c *Cache[K, V]) Resize(size int) (evicted int) {
+	if size <= 0 {
+		size = 1
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.cur.Load()
+	next := newEngine(size, c.opts)
+	old.Range(func(method, path string, value any) bool {
+		if next.AddValue(method, path, value) {
+			evicted++
+		}
+		return true
+	})
+	// Entries the new engine shed under contention did not fit either.
+	if _, _, drops, _ := next.Stats(); drops > 0 {
+		evicted += int(drops)
+	}
+	c.cur.Store(next)
+	runtime.KeepAlive(old)
+	return evicted
+}
+
+// unwrap converts a stored entry back to the caller's value, treating an
+// entry for a different key with the same key string as a miss.
+func unwrap[K comparable, V any](v any, ok bool, key K) (value V, found bool) {
+	if !ok {
+		return value, false
+	}
+	e, ok := v.(*entry[K, V])
+	if !ok || e.key != key {
+		return value, false
+	}
+	return e.value, true
+}
+
+// keyString maps a key to the path it is stored under. Distinct keys that
+// format alike share a slot, so they evict rather than alias each other.
+func keyString[K comparable](key K) string {
+	switch k := any(key).(type) {
+	case string:
+		return k
+	case int:
+		return strconv.Itoa(k)
+	case int64:
+		return strconv.FormatInt(k, 10)
+	case uint64:
+		return strconv.FormatUint(k, 10)
+	case int32:
+		return strconv.FormatInt(int64(k), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(k), 10)
+	default:
+		return fmt.Sprintf("%#v", key)
+	}
+}
@@ -0,0 +1,163 @@
+package compat
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+type point struct{ X, Y int }
+
+// conformance is the behaviour every Cache configuration must share; it is
+// run for several key types and engine modes.
+func conformance[K comparable](t *testing.T, key func(i int) K, opts ...liteLRU.Option) {
+	t.Helper()
+	if _, err := New[K, int](0, opts...); err == nil {
+		t.Fatal("New(0) should fail")
+	}
+
+	c, err := New[K, int](128, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("AddGet", func(t *testing.T) {
+		if c.Add(key(1), 1) {
+			t.Fatal("Add into an empty cache evicted")
+		}
+		if c.Add(key(1), 11) {
+			t.Fatal("updating a key evicted")
+		}
+		if v, ok := c.Get(key(1)); !ok || v != 11 {
+			t.Fatalf("Get = %v %v", v, ok)
+		}
+		if _, ok := c.Get(key(2)); ok {
+			t.Fatal("Get of a missing key succeeded")
+		}
+	})
+
+	t.Run("ContainsPeekRemove", func(t *testing.T) {
+		c.Add(key(3), 3)
+		if !c.Contains(key(3)) || c.Contains(key(4)) {
+			t.Fatal("Contains is wrong")
+		}
+		if v, ok := c.Peek(key(3)); !ok || v != 3 {
+			t.Fatalf("Peek = %v %v", v, ok)
+		}
+		if !c.Remove(key(3)) || c.Remove(key(3)) {
+			t.Fatal("Remove should report presence once")
+		}
+		if c.Contains(key(3)) {
+			t.Fatal("removed key still present")
+		}
+	})
+
+	t.Run("KeysValuesLen", func(t *testing.T) {
+		c.Purge()
+		for i := 0; i < 10; i++ {
+			c.Add(key(i), i)
+		}
+		if c.Len() != 10 {
+			t.Fatalf("Len = %d", c.Len())
+		}
+		keys, values := c.Keys(), c.Values()
+		if len(keys) != 10 || len(values) != 10 {
+			t.Fatalf("Keys %v Values %v", keys, values)
+		}
+		for i := 0; i < 10; i++ {
+			if !slices.Contains(keys, key(i)) || !slices.Contains(values, i) {
+				t.Fatalf("key %d missing from Keys/Values", i)
+			}
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		c.Purge()
+		if c.Len() != 0 || c.Contains(key(1)) {
+			t.Fatal("Purge left entries behind")
+		}
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		evictions := 0
+		for i := 0; i < 2000; i++ {
+			if c.Add(key(i), i) {
+				evictions++
+			}
+		}
+		if c.Len() > 128 || evictions == 0 {
+			t.Fatalf("Len = %d after %d evictions", c.Len(), evictions)
+		}
+	})
+
+	t.Run("Resize", func(t *testing.T) {
+		c.Purge()
+		for i := 0; i < 100; i++ {
+			c.Add(key(i), i)
+		}
+		n := c.Len()
+		if evicted := c.Resize(1024); evicted != 0 || c.Len() != n {
+			t.Fatalf("growing evicted %d, Len %d -> %d", evicted, n, c.Len())
+		}
+		if v, ok := c.Get(key(42)); !ok || v != 42 {
+			t.Fatalf("entry lost by Resize: %v %v", v, ok)
+		}
+		for i := 100; i < 1000; i++ {
+			c.Add(key(i), i)
+		}
+		before := c.Len()
+		evicted := c.Resize(64)
+		if c.Len() > 64 || evicted == 0 || c.Len()+evicted < before {
+			t.Fatalf("shrinking: Len %d -> %d, evicted %d", before, c.Len(), evicted)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					k := key(w*1000 + i%50)
+					c.Add(k, i)
+					if v, ok := c.Get(k); ok && v < 0 {
+						t.Errorf("bad value %d", v)
+					}
+					c.Contains(k)
+					if i%100 == 0 {
+						c.Remove(k)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	})
+}
+
+func TestConformance(t *testing.T) {
+	t.Run("string", func(t *testing.T) { conformance(t, func(i int) string { return fmt.Sprintf("key-%d", i) }) })
+	t.Run("int", func(t *testing.T) { conformance(t, func(i int) int { return i }) })
+	t.Run("struct", func(t *testing.T) { conformance(t, func(i int) point { return point{i, -i} }) })
+	t.Run("arena", func(t *testing.T) {
+		conformance(t, func(i int) string { return fmt.Sprintf("key-%d", i) }, liteLRU.WithArena(0))
+	})
+}
+
+func TestCollidingKeyStrings(t *testing.T) {
+	c, _ := New[any, string](64)
+	c.Add(1, "int")
+	c.Add("1", "string")
+	if v, ok := c.Get(1); ok && v != "int" {
+		t.Fatalf("int key read the string key's value %q", v)
+	}
+	if v, ok := c.Get("1"); !ok || v != "string" {
+		t.Fatalf("Get(\"1\") = %q %v", v, ok)
+	}
+	if c.Remove(1) {
+		t.Fatal("Remove(1) removed the entry of \"1\"")
+	}
+}
@@ -0,0 +1,91 @@
+package liteLRU
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// epochStripe counts borrowers pinned in each of the three live epochs.
+// Stripes are padded to the detected line size by newEpochDomain so that
+// borrowers on different stripes never share a cache line.
+type epochStripe struct {
+	active [3]atomic.Int64
+}
+
+// epochDomain implements epoch-based reclamation for borrowed reads (View).
+//
+// A borrower pins the current global epoch on the stripe of the slot it
+// reads, for as long as it holds references into the slot. Writers that
+// unlink memory a borrower might still be reading retire it into the bucket
+// of the epoch current at unlink time; the bucket is reclaimed once the
+// global epoch has advanced twice, which can only happen after every
+// borrower pinned at or before the unlink has left.
+//
+// Because the stripe follows the slot, a writer only has to look at the
+// stripe of the slot it rewrites to know whether the old memory may be
+// recycled in place: one cache line, which is never written while no View
+// on a slot of that stripe is in progress.
+type epochDomain struct {
+	global  atomic.Uint64
+	stripes []byte // 64 epochStripes, stride bytes apart
+	stride  uintptr
+}
+
+// newEpochDomain lays out the stripes of an epoch domain, each padded to line
+// bytes.
+func newEpochDomain(line uintptr) epochDomain {
+	stride := padTo(unsafe.Sizeof(epochStripe{}), line)
+	return epochDomain{stripes: make([]byte, 64*stride), stride: stride}
+}
+
+// stripe returns the stripe of slot idx.
+func (d *epochDomain) stripe(idx uint32) *epochStripe {
+	return (*epochStripe)(unsafe.Add(unsafe.Pointer(unsafe.SliceData(d.stripes)), uintptr(idx%64)*d.stride))
+}
+
+// enter pins the calling borrower of slot idx to the current epoch and
+// returns it. The borrower must then re-validate the slot's seqlock: a writer
+// that begins rewriting the slot after the pin sees it in quiescent.
+func (d *epochDomain) enter(idx uint32) uint64 {
+	st := d.stripe(idx)
+	for {
+		e := d.global.Load()
+		st.active[e%3].Add(1)
+		// Re-check so a pin can never land in an epoch that has already been
+		// declared free of borrowers.
+		if d.global.Load() == e {
+			return e
+		}
+		st.active[e%3].Add(-1)
+	}
+}
+
+// exit unpins a borrower of slot idx that entered at epoch e.
+func (d *epochDomain) exit(idx uint32, e uint64) {
+	d.stripe(idx).active[e%3].Add(-1)
+}
+
+// quiescent reports whether no borrower is pinned on the stripe of slot idx,
+// i.e. whether a writer holding the slot's seqlock odd may recycle memory the
+// slot referenced in place: a borrower that pins later fails its re-check.
+func (d *epochDomain) quiescent(idx uint32) bool {
+	st := d.stripe(idx)
+	return st.active[0].Load()|st.active[1].Load()|st.active[2].Load() == 0
+}
+
+// tryAdvance moves the global epoch from e to e+1 if no borrower is still
+// pinned in e-1. On success it returns the bucket of epoch e-1, whose retired
+// memory can no longer be referenced by anyone and may be reclaimed.
+func (d *epochDomain) tryAdvance() (bucket uint64, ok bool) {
+	e := d.global.Load()
+	prev := (e + 2) % 3
+	for i := uint32(0); i < 64; i++ {
+		if d.stripe(i).active[prev].Load() != 0 {
+			return 0, false
+		}
+	}
+	if !d.global.CompareAndSwap(e, e+1) {
+		return 0, false
+	}
+	return prev, true
+}
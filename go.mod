@@ -3,6 +3,6 @@ module github.com/xDarkicex/liteLRU
 go 1.25.7
 
 require (
-	github.com/xDarkicex/memory v1.2.2 // indirect
-	golang.org/x/sys v0.43.0 // indirect
+	github.com/xDarkicex/memory v1.2.2
+	golang.org/x/sys v0.43.0
 )
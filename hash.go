@@ -0,0 +1,114 @@
+package liteLRU
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"unsafe"
+)
+
+// WithFNVHash selects the byte-at-a-time FNV-1a key hash used before the
+// word-at-a-time hash became the default. It does not reproduce the placement
+// of earlier releases, which hashed the method before the path: the set is now
+// chosen from the path hash alone, so that all methods of a path share a set,
+// and the method is hashed after the path. It is slower for long paths and
+// exists for comparison benchmarks.
+func WithFNVHash() Option {
+	return func(cfg *config) {
+		cfg.fnv = true
+	}
+}
+
+// Primes of the word-at-a-time hash, shared with xxHash64.
+const (
+	prime1 uint64 = 11400714785074694791
+	prime2 uint64 = 14029467366897019727
+	prime3 uint64 = 1609587929392839161
+	prime4 uint64 = 9650029242287828579
+	prime5 uint64 = 2870177450012600261
+)
+
+// wordHash hashes s continuing from seed, eight bytes per step. Each step is
+// an xxHash64 round, which is a bijection of the input word for a given
+// state, and the result goes through the xxHash64 avalanche, so every output
+// bit (the set index in the low bits as well as the signature bytes above
+// bit 32) depends on every input bit.
+func wordHash(seed uint64, s string) uint64 {
+	h := seed + prime5 + uint64(len(s))
+	b := unsafe.Slice(unsafe.StringData(s), len(s))
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= bits.RotateLeft64(binary.LittleEndian.Uint64(b)*prime2, 31) * prime1
+		h = bits.RotateLeft64(h, 27)*prime1 + prime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * prime1
+		h = bits.RotateLeft64(h, 23)*prime2 + prime3
+		b = b[4:]
+	}
+	for ; len(b) > 0; b = b[1:] {
+		h ^= uint64(b[0]) * prime5
+		h = bits.RotateLeft64(h, 11) * prime1
+	}
+	h ^= h >> 33
+	h *= prime2
+	h ^= h >> 29
+	h *= prime3
+	h ^= h >> 32
+	return h
+}
+
+// wordPath hashes a path with wordHash.
+func wordPath(path string) uint64 {
+	return wordHash(0, path)
+}
+
+// wordRoute continues a path hash with the method, so the route hash costs
+// one pass over the path and the path-only prefix comes for free.
+func wordRoute(pathHash uint64, method string) uint64 {
+	return wordHash(pathHash, method)
+}
+
+// wordExtra continues a path hash with the extra key parts. Each part is
+// seeded with its position and length so parts cannot run into each other.
+func wordExtra(hash uint64, extra *[MaxKeyExtra]string) uint64 {
+	for i, n := 0, extraCount(extra); i < n; i++ {
+		hash = wordHash(hash^(uint64(len(extra[i]))<<8|uint64(i+1)), extra[i])
+	}
+	return hash
+}
+
+// fnvPath hashes a path into a fast 64-bit non-cryptographic hash (FNV-1a variant).
+func fnvPath(path string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(path); i++ {
+		hash ^= uint64(path[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// fnvRoute continues a path hash with the method, so the route hash costs
+// one pass over the path and the path-only prefix comes for free.
+func fnvRoute(pathHash uint64, method string) uint64 {
+	hash := pathHash ^ uint64('/')
+	hash *= 1099511628211
+	for i := 0; i < len(method); i++ {
+		hash ^= uint64(method[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// fnvExtra continues a path hash with the extra key parts. Each part is
+// prefixed with its position and length so parts cannot run into each other.
+func fnvExtra(hash uint64, extra *[MaxKeyExtra]string) uint64 {
+	for i, n := 0, extraCount(extra); i < n; i++ {
+		part := extra[i]
+		hash ^= uint64(len(part))<<8 | uint64(i+1)
+		hash *= 1099511628211
+		for j := 0; j < len(part); j++ {
+			hash ^= uint64(part[j])
+			hash *= 1099511628211
+		}
+	}
+	return hash
+}
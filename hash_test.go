@@ -0,0 +1,130 @@
+package liteLRU
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestWordHashAvalanche flips every input bit of random paths and checks
+// that each output bit the cache uses flips about half the time.
+func TestWordHashAvalanche(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	var flips [64]int
+	trials := 0
+	for round := 0; round < 200; round++ {
+		b := make([]byte, 1+r.Intn(40))
+		r.Read(b)
+		base := wordPath(string(b))
+		for i := 0; i < len(b)*8; i++ {
+			b[i/8] ^= 1 << (i % 8)
+			diff := base ^ wordPath(string(b))
+			b[i/8] ^= 1 << (i % 8)
+			for ; diff != 0; diff &= diff - 1 {
+				flips[bits.TrailingZeros64(diff)]++
+			}
+			trials++
+		}
+	}
+	// Set index in the low bits, route and path signatures from bit 32 up.
+	for _, bit := range []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 32, 33, 36, 39, 40, 44, 47, 48, 52, 55} {
+		if p := float64(flips[bit]) / float64(trials); p < 0.45 || p > 0.55 {
+			t.Errorf("output bit %d flips with probability %.3f", bit, p)
+		}
+	}
+}
+
+func TestWordHashLengths(t *testing.T) {
+	// Every length class of the tail handling yields distinct hashes, and
+	// the method continues the path hash rather than restarting it.
+	seen := make(map[uint64]string)
+	for n := 0; n <= 33; n++ {
+		s := strings.Repeat("a", n)
+		h := wordPath(s)
+		if prev, dup := seen[h]; dup {
+			t.Fatalf("%q and %q hash alike", prev, s)
+		}
+		seen[h] = s
+	}
+	if wordRoute(wordPath("/ab"), "GET") == wordRoute(wordPath("/a"), "bGET") {
+		t.Fatal("route hash does not separate path from method")
+	}
+}
+
+func TestFNVHashOption(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithFNVHash()}} {
+		c := NewLRUCache(1024, 10, opts...)
+		for i := 0; i < 500; i++ {
+			c.Add("GET", fmt.Sprintf("/api/v1/users/%d/profile", i), nil, []Param{{Key: "id", Value: fmt.Sprint(i)}})
+		}
+		hits := 0
+		for i := 0; i < 500; i++ {
+			if _, p, ok := c.Get("GET", fmt.Sprintf("/api/v1/users/%d/profile", i), nil); ok {
+				if p[0].Value != fmt.Sprint(i) {
+					t.Fatalf("wrong params %+v", p)
+				}
+				hits++
+			}
+		}
+		if hits < 450 {
+			t.Errorf("fnv=%v: %d/500 hits", c.fnv, hits)
+		}
+		c.Close()
+	}
+
+	c := NewLRUCache(1024, 10, WithFNVHash())
+	defer c.Close()
+	g, _, _ := c.locatePath("/users/42", nil, 0)
+	if want := uint32(fnvPath("/users/42") % uint64(c.numSets)); g != want {
+		t.Fatalf("FNV placement changed: set %d, want %d", g, want)
+	}
+}
+
+var hashSink uint64
+
+// BenchmarkRouteHash compares the word-at-a-time and FNV route hashes over
+// growing path lengths.
+func BenchmarkRouteHash(b *testing.B) {
+	for _, n := range []int{8, 24, 64, 128, 512} {
+		path := "/" + strings.Repeat("seg/", n/4)[:n-1]
+		b.Run(fmt.Sprintf("word/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				hashSink += wordRoute(wordPath(path), "GET")
+			}
+		})
+		b.Run(fmt.Sprintf("fnv/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				hashSink += fnvRoute(fnvPath(path), "GET")
+			}
+		})
+	}
+}
+
+// BenchmarkGetPathLength measures Get hits end to end with both hashes.
+func BenchmarkGetPathLength(b *testing.B) {
+	for _, n := range []int{8, 24, 64, 128, 512} {
+		for _, hash := range []struct {
+			name string
+			opts []Option
+		}{{"word", nil}, {"fnv", []Option{WithFNVHash()}}} {
+			b.Run(fmt.Sprintf("%s/%d", hash.name, n), func(b *testing.B) {
+				c := NewLRUCache(4096, 10, hash.opts...)
+				defer c.Close()
+				paths := make([]string, 1024)
+				for i := range paths {
+					p := fmt.Sprintf("/%d/", i) + strings.Repeat("x", n)
+					paths[i] = p[:n]
+					c.Add("GET", paths[i], nil, nil)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					c.Get("GET", paths[i&1023], nil)
+				}
+			})
+		}
+	}
+}
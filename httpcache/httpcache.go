@@ -0,0 +1,311 @@
+// Package httpcache provides HTTP response caching on top of a liteLRU cache:
+// a server-side middleware and (see Transport) a client-side RoundTripper.
+//
+// Responses are stored as ordinary liteLRU entries. Only GET responses are
+// stored, under the cache's GET method key plus host, path and sorted query
+// string, and HEAD requests reuse them; responses carrying a Vary header
+// additionally key on the listed request header values. The
+// stored params hold the status line, freshness timestamps, headers and body,
+// using ":"-prefixed pseudo keys that can never collide with header names.
+package httpcache
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+// Option configures a Middleware or Transport.
+type Option func(*config)
+
+type config struct {
+	defaultTTL time.Duration
+	maxBody    int
+	now        func() time.Time
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{maxBody: 1 << 20, now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithDefaultTTL caches responses that carry no explicit freshness
+// information (Cache-Control max-age/s-maxage or Expires) for ttl. By default
+// such responses are not cached.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(cfg *config) { cfg.defaultTTL = ttl }
+}
+
+// WithMaxBodySize sets the largest response body that is cached; larger
+// responses are streamed through untouched. The default is 1 MiB.
+func WithMaxBodySize(n int) Option {
+	return func(cfg *config) { cfg.maxBody = n }
+}
+
+// WithClock replaces time.Now, mainly for tests.
+func WithClock(now func() time.Time) Option {
+	return func(cfg *config) { cfg.now = now }
+}
+
+// Pseudo keys of the stored params. Header names never start with ':'.
+const (
+	keyStatus  = ":status"
+	keyStored  = ":stored"
+	keyExpires = ":expires"
+	keyVary    = ":vary"
+	keyBody    = ":body"
+)
+
+// hopHeaders are connection-scoped and never stored.
+var hopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// entry is a stored response decoded from cache params.
+type entry struct {
+	status  int
+	header  http.Header
+	body    string
+	stored  time.Time
+	expires time.Time
+}
+
+// encode flattens a response into cache params.
+func encode(status int, header http.Header, body []byte, stored, expires time.Time) []liteLRU.Param {
+	params := make([]liteLRU.Param, 0, 4+len(header))
+	params = append(params,
+		liteLRU.Param{Key: keyStatus, Value: strconv.Itoa(status)},
+		liteLRU.Param{Key: keyStored, Value: strconv.FormatInt(stored.UnixNano(), 10)},
+		liteLRU.Param{Key: keyExpires, Value: strconv.FormatInt(expires.UnixNano(), 10)},
+	)
+	for name, values := range header {
+		if hopHeaders[name] {
+			continue
+		}
+		for _, v := range values {
+			params = append(params, liteLRU.Param{Key: name, Value: v})
+		}
+	}
+	return append(params, liteLRU.Param{Key: keyBody, Value: string(body)})
+}
+
+// decode rebuilds a stored response. Strings are cloned because params may
+// alias arena memory. ok is false for vary markers and malformed entries.
+func decode(params []liteLRU.Param) (e entry, ok bool) {
+	if len(params) < 4 || params[0].Key != keyStatus {
+		return e, false
+	}
+	status, err := strconv.Atoi(params[0].Value)
+	if err != nil {
+		return e, false
+	}
+	stored, err1 := strconv.ParseInt(params[1].Value, 10, 64)
+	expires, err2 := strconv.ParseInt(params[2].Value, 10, 64)
+	if err1 != nil || err2 != nil {
+		return e, false
+	}
+	e.status = status
+	e.stored = time.Unix(0, stored)
+	e.expires = time.Unix(0, expires)
+	e.header = make(http.Header, len(params)-4)
+	for _, p := range params[3:] {
+		if p.Key == keyBody {
+			e.body = strings.Clone(p.Value)
+			continue
+		}
+		e.header[p.Key] = append(e.header[p.Key], strings.Clone(p.Value))
+	}
+	return e, true
+}
+
+// varyMarker is stored under the primary key of responses with a Vary header;
+// the response itself lives under the secondary key derived from it.
+func varyMarker(names []string) []liteLRU.Param {
+	return []liteLRU.Param{{Key: keyVary, Value: strings.Join(names, ",")}}
+}
+
+// lookup finds the stored response for r, following a vary marker if present.
+func lookup(cache *liteLRU.LRUCache, r *http.Request) (entry, bool) {
+	var buf [32]liteLRU.Param
+	key := primaryKey(r)
+	_, params, ok := cache.Get(http.MethodGet, key, buf[:0])
+	if !ok {
+		return entry{}, false
+	}
+	if len(params) == 1 && params[0].Key == keyVary {
+		names := strings.Split(params[0].Value, ",")
+		if _, params, ok = cache.Get(http.MethodGet, secondaryKey(key, names, r.Header), buf[:0]); !ok {
+			return entry{}, false
+		}
+	}
+	return decode(params)
+}
+
+// store saves a response for r under the right key, writing a vary marker
+// first when the response varies on request headers.
+func store(cache *liteLRU.LRUCache, r *http.Request, status int, header http.Header, body []byte, stored, expires time.Time) {
+	key := primaryKey(r)
+	if names := varyNames(header); len(names) > 0 {
+		cache.Add(http.MethodGet, key, nil, varyMarker(names))
+		key = secondaryKey(key, names, r.Header)
+	}
+	cache.Add(http.MethodGet, key, nil, encode(status, header, body, stored, expires))
+}
+
+// primaryKey is host + path + canonical (sorted) query.
+func primaryKey(r *http.Request) string {
+	key := r.Host + r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		if q, err := url.ParseQuery(r.URL.RawQuery); err == nil {
+			key += "?" + q.Encode()
+		} else {
+			key += "?" + r.URL.RawQuery
+		}
+	}
+	return key
+}
+
+// secondaryKey extends the primary key with the request's values of the
+// headers named by Vary.
+func secondaryKey(primary string, names []string, h http.Header) string {
+	var b strings.Builder
+	b.WriteString(primary)
+	for _, name := range names {
+		b.WriteByte(0)
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strings.Join(h.Values(name), ","))
+	}
+	return b.String()
+}
+
+// varyNames returns the canonical header names listed in Vary.
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// cacheControl holds the parsed directives of Cache-Control headers.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(part, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns a delta-seconds directive value.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// cacheableStatus lists the status codes that are cacheable by default
+// (RFC 9110 section 15.1).
+var cacheableStatus = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// freshness decides whether a response may be stored by a shared cache and
+// for how long, per RFC 9111: no-store, private and no-cache responses and
+// Vary: * are never stored; s-maxage beats max-age beats Expires, and the
+// configured default applies only when none is present.
+func freshness(status int, h http.Header, now time.Time, defaultTTL time.Duration) (time.Duration, bool) {
+	if !cacheableStatus[status] || h.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	cc := parseCacheControl(h)
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return 0, false
+	}
+	for _, name := range varyNames(h) {
+		if name == "*" {
+			return 0, false
+		}
+	}
+	// Time already spent in upstream caches counts against max-age.
+	var age time.Duration
+	if n, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && n > 0 {
+		age = time.Duration(n) * time.Second
+	}
+	if ttl, ok := cc.seconds("s-maxage"); ok {
+		return ttl - age, ttl > age
+	}
+	if ttl, ok := cc.seconds("max-age"); ok {
+		return ttl - age, ttl > age
+	}
+	if exp := h.Get("Expires"); exp != "" {
+		t, err := http.ParseTime(exp)
+		if err != nil {
+			return 0, false // invalid Expires means already expired
+		}
+		date := now
+		if d, err := http.ParseTime(h.Get("Date")); err == nil {
+			date = d
+		}
+		ttl := t.Sub(date)
+		return ttl, ttl > 0
+	}
+	return defaultTTL, defaultTTL > 0
+}
+
+// etagMatch reports whether an If-None-Match header matches etag using the
+// weak comparison function.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+// Middleware caches the responses of an http.Handler in a liteLRU cache,
+// acting as a shared cache in front of the handler.
+//
+// GET and HEAD requests are served from the cache while the stored response
+// is fresh; HEAD requests are answered from the GET response. Requests with
+// an Authorization header or Cache-Control: no-store bypass the cache, and
+// Cache-Control: no-cache or max-age on the request limit which stored
+// responses may be used. Responses are stored according to their status,
+// Cache-Control, Expires and Vary headers (see freshness). A request whose
+// If-None-Match matches the ETag of the response gets a 304, both on hits and
+// on misses, because the wrapped handler is always invoked unconditionally so
+// that a full response can be stored.
+type Middleware struct {
+	cache *liteLRU.LRUCache
+	cfg   config
+}
+
+// NewMiddleware creates a response-caching middleware backed by cache.
+func NewMiddleware(cache *liteLRU.LRUCache, opts ...Option) *Middleware {
+	return &Middleware{cache: cache, cfg: newConfig(opts)}
+}
+
+// Handler wraps next with response caching.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reqCC := parseCacheControl(r.Header)
+		if reqCC.has("no-store") || r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := m.cfg.now()
+		if !reqCC.has("no-cache") {
+			if e, ok := lookup(m.cache, r); ok && now.Before(e.expires) {
+				if maxAge, ok := reqCC.seconds("max-age"); !ok || now.Sub(e.stored) <= maxAge {
+					serve(w, r, e, now.Sub(e.stored))
+					return
+				}
+			}
+		}
+
+		// Miss: run the handler unconditionally as a GET so the complete
+		// response can be stored, buffering it unless it grows too large.
+		inner := r.Clone(r.Context())
+		inner.Method = http.MethodGet
+		inner.Header.Del("If-None-Match")
+		inner.Header.Del("If-Modified-Since")
+
+		rec := &recorder{w: w, header: make(http.Header), max: m.cfg.maxBody, head: r.Method == http.MethodHead}
+		next.ServeHTTP(rec, inner)
+		if rec.streaming {
+			return
+		}
+		if !rec.wroteHeader {
+			rec.status = http.StatusOK
+		}
+
+		if ttl, ok := freshness(rec.status, rec.header, now, m.cfg.defaultTTL); ok {
+			store(m.cache, r, rec.status, rec.header, rec.body.Bytes(), now, now.Add(ttl))
+		}
+		serve(w, r, entry{status: rec.status, header: rec.header, body: rec.body.String()}, -1)
+	})
+}
+
+// serve writes a stored or freshly recorded response, answering with 304 if
+// the request's If-None-Match matches. age < 0 means the response did not
+// come from the cache and gets no Age header.
+func serve(w http.ResponseWriter, r *http.Request, e entry, age time.Duration) {
+	h := w.Header()
+	for name, values := range e.header {
+		h[name] = values
+	}
+	if age >= 0 {
+		h.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && e.status == http.StatusOK && etagMatch(inm, e.header.Get("ETag")) {
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(e.status)
+	if r.Method != http.MethodHead {
+		w.Write([]byte(e.body))
+	}
+}
+
+// recorder buffers a handler's response so it can be stored before it is
+// sent. If the body outgrows max, or the handler flushes, it switches to
+// streaming straight to the client and the response is not cached.
+type recorder struct {
+	w           http.ResponseWriter
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	streaming   bool
+	head        bool // the client asked for HEAD; streamed bodies are dropped
+	max         int
+}
+
+func (rec *recorder) Header() http.Header {
+	if rec.streaming {
+		return rec.w.Header()
+	}
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.streaming {
+		if rec.head {
+			return len(b), nil
+		}
+		return rec.w.Write(b)
+	}
+	if rec.body.Len()+len(b) > rec.max {
+		if err := rec.stream(); err != nil {
+			return 0, err
+		}
+		return rec.Write(b)
+	}
+	return rec.body.Write(b)
+}
+
+// Flush implements http.Flusher; flushed responses are never cached.
+func (rec *recorder) Flush() {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.streaming {
+		if rec.stream() != nil {
+			return
+		}
+	}
+	if f, ok := rec.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// stream sends the buffered header and body and switches to pass-through.
+func (rec *recorder) stream() error {
+	rec.streaming = true
+	h := rec.w.Header()
+	for name, values := range rec.header {
+		h[name] = values
+	}
+	rec.w.WriteHeader(rec.status)
+	if rec.head {
+		return nil
+	}
+	_, err := rec.w.Write(rec.body.Bytes())
+	rec.body.Reset()
+	return err
+}
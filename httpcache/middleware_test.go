@@ -0,0 +1,208 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+// fakeClock is a manually advanced clock for freshness tests.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestMiddleware(t *testing.T, h http.HandlerFunc, opts ...Option) (http.Handler, *atomic.Int64, *fakeClock) {
+	t.Helper()
+	cache := liteLRU.NewLRUCache(256, 64)
+	t.Cleanup(cache.Close)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var calls atomic.Int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		h(w, r)
+	})
+	opts = append([]Option{WithClock(clock.now)}, opts...)
+	return NewMiddleware(cache, opts...).Handler(next), &calls, clock
+}
+
+func do(h http.Handler, method, target string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Add(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareCachesFreshResponses(t *testing.T) {
+	h, calls, clock := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+
+	first := do(h, "GET", "/greet")
+	clock.advance(10 * time.Second)
+	second := do(h, "GET", "/greet")
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler called %d times", calls.Load())
+	}
+	if second.Body.String() != "hello" || second.Code != 200 || second.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("bad cached response: %d %q %v", second.Code, second.Body.String(), second.Header())
+	}
+	if first.Header().Get("Age") != "" || second.Header().Get("Age") != "10" {
+		t.Fatalf("Age headers %q / %q", first.Header().Get("Age"), second.Header().Get("Age"))
+	}
+
+	clock.advance(51 * time.Second)
+	do(h, "GET", "/greet")
+	if calls.Load() != 2 {
+		t.Fatal("expired response was served")
+	}
+}
+
+func TestMiddlewareHonoursCacheControl(t *testing.T) {
+	for _, cc := range []string{"no-store", "private, max-age=60", "no-cache", "max-age=0"} {
+		h, calls, _ := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", cc)
+			w.Write([]byte("x"))
+		})
+		do(h, "GET", "/")
+		do(h, "GET", "/")
+		if calls.Load() != 2 {
+			t.Fatalf("%q response was cached", cc)
+		}
+	}
+
+	h, calls, _ := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+	})
+	do(h, "GET", "/")
+	do(h, "GET", "/", "Cache-Control", "no-cache")
+	do(h, "GET", "/", "Authorization", "Bearer x")
+	do(h, "POST", "/")
+	if calls.Load() != 4 {
+		t.Fatalf("request directives ignored: %d calls", calls.Load())
+	}
+	do(h, "GET", "/")
+	if calls.Load() != 4 {
+		t.Fatal("no-cache request did not refresh the stored response")
+	}
+}
+
+func TestMiddlewareExpiresAndDefaultTTL(t *testing.T) {
+	var clock *fakeClock
+	h, calls, clock := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", clock.now().Format(http.TimeFormat))
+		w.Header().Set("Expires", clock.now().Add(30*time.Second).Format(http.TimeFormat))
+	})
+	do(h, "GET", "/e")
+	clock.advance(20 * time.Second)
+	do(h, "GET", "/e")
+	clock.advance(20 * time.Second)
+	do(h, "GET", "/e")
+	if calls.Load() != 2 {
+		t.Fatalf("Expires not honoured: %d calls", calls.Load())
+	}
+
+	h, calls, _ = newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {}, WithDefaultTTL(time.Minute))
+	do(h, "GET", "/d")
+	do(h, "GET", "/d")
+	if calls.Load() != 1 {
+		t.Fatal("default TTL not applied")
+	}
+}
+
+func TestMiddlewareKeyIncludesSortedQueryAndVary(t *testing.T) {
+	h, calls, _ := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.URL.RawQuery + "|" + r.Header.Get("Accept-Language")))
+	})
+
+	do(h, "GET", "/q?a=1&b=2", "Accept-Language", "en")
+	if got := do(h, "GET", "/q?b=2&a=1", "Accept-Language", "en").Body.String(); got != "a=1&b=2|en" {
+		t.Fatalf("query order should not matter, got %q", got)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected hit, %d calls", calls.Load())
+	}
+	if got := do(h, "GET", "/q?a=1&b=2", "Accept-Language", "fr").Body.String(); got != "a=1&b=2|fr" {
+		t.Fatalf("Vary ignored, got %q", got)
+	}
+	do(h, "GET", "/q?a=1&b=2", "Accept-Language", "fr")
+	do(h, "GET", "/q?a=1&b=2", "Accept-Language", "en")
+	if calls.Load() != 2 {
+		t.Fatalf("each variant should be cached once, %d calls", calls.Load())
+	}
+	do(h, "GET", "/q?a=2")
+	if calls.Load() != 3 {
+		t.Fatal("different query must miss")
+	}
+}
+
+func TestMiddlewareETag(t *testing.T) {
+	h, calls, _ := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("handler saw a conditional request")
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("body"))
+	})
+
+	miss := do(h, "GET", "/tag", "If-None-Match", `"v1"`)
+	if miss.Code != http.StatusNotModified || miss.Body.Len() != 0 {
+		t.Fatalf("miss with matching etag: %d %q", miss.Code, miss.Body.String())
+	}
+	hit := do(h, "GET", "/tag", "If-None-Match", `"v0", W/"v1"`)
+	if hit.Code != http.StatusNotModified || hit.Header().Get("ETag") != `"v1"` {
+		t.Fatalf("hit with matching etag: %d", hit.Code)
+	}
+	if full := do(h, "GET", "/tag", "If-None-Match", `"v2"`); full.Code != 200 || full.Body.String() != "body" {
+		t.Fatalf("non-matching etag: %d %q", full.Code, full.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("handler called %d times", calls.Load())
+	}
+}
+
+func TestMiddlewareHeadAndLargeBodies(t *testing.T) {
+	big := strings.Repeat("x", 100)
+	h, calls, _ := newTestMiddleware(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.URL.Path == "/big" {
+			w.Write([]byte(big[:50]))
+			w.Write([]byte(big[50:]))
+			return
+		}
+		w.Write([]byte("small"))
+	}, WithMaxBodySize(64))
+
+	if head := do(h, "HEAD", "/small"); head.Code != 200 || head.Body.Len() != 0 {
+		t.Fatalf("HEAD: %d %q", head.Code, head.Body.String())
+	}
+	if get := do(h, "GET", "/small"); get.Body.String() != "small" {
+		t.Fatalf("GET after HEAD: %q", get.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Fatal("HEAD and GET should share the stored response")
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := do(h, "GET", "/big").Body.String(); got != big {
+			t.Fatalf("large body truncated: %d bytes", len(got))
+		}
+	}
+	if calls.Load() != 3 {
+		t.Fatal("oversized response should not be cached")
+	}
+}
@@ -0,0 +1,248 @@
+package httpcache
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+// Transport is an http.RoundTripper that caches upstream responses in a
+// liteLRU cache, acting as a shared cache per RFC 9111: one Transport may
+// serve requests on behalf of many users, so responses marked private or
+// carrying Set-Cookie are never stored or handed to another request,
+// requests with an Authorization header bypass the cache, and s-maxage takes
+// precedence over max-age.
+//
+// Cacheable GET responses are served from the cache while fresh; HEAD
+// requests are answered from a fresh GET response and otherwise passed
+// through. Stale responses that carry an ETag or Last-Modified validator are
+// revalidated with a conditional request, and a 304 refreshes the stored
+// headers and freshness without transferring the body again. Responses with
+// Cache-Control: no-cache are stored only if they have a validator, and are
+// then revalidated on every use. Concurrent misses for the same resource are
+// coalesced into a single upstream request; if its response turns out not to
+// be storable, each waiting request is sent upstream itself, and if it fails
+// because its own caller gave up, the waiting requests try again.
+type Transport struct {
+	cache   *liteLRU.LRUCache
+	base    http.RoundTripper
+	cfg     config
+	flights flightGroup
+}
+
+// NewTransport creates a caching transport backed by cache that sends
+// requests through base (http.DefaultTransport if nil).
+func NewTransport(cache *liteLRU.LRUCache, base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{cache: cache, base: base, cfg: newConfig(opts)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") {
+		return t.base.RoundTrip(req)
+	}
+
+	now := t.cfg.now()
+	e, found := lookup(t.cache, req)
+	if found && !reqCC.has("no-cache") && now.Before(e.expires) {
+		if maxAge, ok := reqCC.seconds("max-age"); !ok || now.Sub(e.stored) <= maxAge {
+			return e.response(req, now.Sub(e.stored)), nil
+		}
+	}
+
+	// Requests the cache cannot store a response for go straight upstream:
+	// HEAD (no body to store) and requests that carry their own validators.
+	if req.Method == http.MethodHead || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	res, leader := t.flights.do(primaryKey(req), func() flightResult {
+		return t.fetch(req, e, found)
+	})
+	if res.err != nil {
+		if !leader && res.canceled && req.Context().Err() == nil {
+			return t.RoundTrip(req) // the leader's caller gave up, this one has not
+		}
+		return nil, res.err
+	}
+	if res.resp != nil {
+		if leader {
+			return res.resp, nil // uncacheable body already streaming to the leader
+		}
+		return t.base.RoundTrip(req)
+	}
+	if !leader && !res.stored {
+		return t.base.RoundTrip(req) // the leader's response is not for sharing
+	}
+	// A coalesced response is only valid for followers that agree with the
+	// leader on every header the response varies on.
+	if !leader && res.leaderReq != nil {
+		for _, name := range varyNames(res.entry.header) {
+			if strings.Join(req.Header.Values(name), ",") != strings.Join(res.leaderReq.Header.Values(name), ",") {
+				return t.base.RoundTrip(req)
+			}
+		}
+	}
+	return res.entry.response(req, -1), nil
+}
+
+// fetch performs the upstream request for a miss or a stale entry, storing
+// the result when it is cacheable.
+func (t *Transport) fetch(req *http.Request, stale entry, found bool) flightResult {
+	out := req
+	revalidate := found && (stale.header.Get("ETag") != "" || stale.header.Get("Last-Modified") != "")
+	if revalidate {
+		out = req.Clone(req.Context())
+		if etag := stale.header.Get("ETag"); etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+		if lm := stale.header.Get("Last-Modified"); lm != "" {
+			out.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		return flightResult{err: err, canceled: req.Context().Err() != nil}
+	}
+	now := t.cfg.now()
+
+	if revalidate && resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		// Freshen the stored response with the headers of the 304.
+		for name, values := range resp.Header {
+			if name != "Content-Length" && !hopHeaders[name] {
+				stale.header[name] = values
+			}
+		}
+		stale.stored = now
+		stored := t.save(req, &stale, now)
+		return flightResult{entry: stale, leaderReq: req, stored: stored}
+	}
+
+	// Buffer the body so it can be stored and shared, unless it is too large.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.cfg.maxBody)+1))
+	if err != nil {
+		resp.Body.Close()
+		return flightResult{err: err, canceled: req.Context().Err() != nil}
+	}
+	if len(body) > t.cfg.maxBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return flightResult{resp: resp}
+	}
+	resp.Body.Close()
+
+	e := entry{status: resp.StatusCode, header: resp.Header, body: string(body), stored: now}
+	stored := t.save(req, &e, now)
+	return flightResult{entry: e, leaderReq: req, stored: stored}
+}
+
+// save stores e if its headers allow, setting its expiry, and reports whether
+// it did. no-cache responses with a validator are stored already stale so
+// every use revalidates.
+func (t *Transport) save(req *http.Request, e *entry, now time.Time) bool {
+	ttl, ok := freshness(e.status, e.header, now, t.cfg.defaultTTL)
+	if !ok {
+		cc := parseCacheControl(e.header)
+		if !cc.has("no-cache") || cc.has("no-store") || cc.has("private") ||
+			(e.header.Get("ETag") == "" && e.header.Get("Last-Modified") == "") {
+			e.expires = now
+			return false
+		}
+		ttl = 0
+	}
+	e.expires = now.Add(ttl)
+	store(t.cache, req, e.status, e.header, []byte(e.body), e.stored, e.expires)
+	return true
+}
+
+// response builds an independent *http.Response for a stored entry. age < 0
+// means the entry was fetched for this request and gets no Age header.
+func (e entry) response(req *http.Request, age time.Duration) *http.Response {
+	resp := &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+	if age >= 0 {
+		resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	}
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+	}
+	return resp
+}
+
+// flightResult is the outcome of one coalesced upstream request: a buffered
+// entry, shared by all callers if it was stored, or an unbuffered response
+// only the leader may consume.
+type flightResult struct {
+	entry     entry
+	leaderReq *http.Request
+	stored    bool // entry was stored, so followers may reuse it
+	resp      *http.Response
+	err       error
+	canceled  bool // err is due to the leader's context ending
+}
+
+// flight is an upstream request in progress.
+type flight struct {
+	done    chan struct{}
+	waiters int
+	res     flightResult
+}
+
+// flightGroup coalesces concurrent upstream requests for the same key.
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[string]*flight
+}
+
+// do runs fn once per key at a time; callers arriving while it runs wait for
+// and share its result. leader reports whether this caller ran fn.
+func (g *flightGroup) do(key string, fn func() flightResult) (res flightResult, leader bool) {
+	g.mu.Lock()
+	if f, ok := g.m[key]; ok {
+		f.waiters++
+		g.mu.Unlock()
+		<-f.done
+		return f.res, false
+	}
+	if g.m == nil {
+		g.m = make(map[string]*flight)
+	}
+	f := &flight{done: make(chan struct{})}
+	g.m[key] = f
+	g.mu.Unlock()
+
+	f.res = fn()
+
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+	close(f.done)
+	return f.res, true
+}
@@ -0,0 +1,321 @@
+package httpcache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+func newTestTransport(t *testing.T, h http.HandlerFunc) (*http.Client, *httptest.Server, *atomic.Int64, *fakeClock) {
+	t.Helper()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	cache := liteLRU.NewLRUCache(256, 64)
+	t.Cleanup(cache.Close)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tr := NewTransport(cache, srv.Client().Transport, WithClock(clock.now))
+	return &http.Client{Transport: tr}, srv, &calls, clock
+}
+
+func fetch(t *testing.T, c *http.Client, method, url string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Add(header[i], header[i+1])
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestTransportCachesFreshResponses(t *testing.T) {
+	c, srv, calls, clock := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("upstream"))
+	})
+
+	fetch(t, c, "GET", srv.URL+"/a")
+	clock.advance(5 * time.Second)
+	resp, body := fetch(t, c, "GET", srv.URL+"/a")
+	if calls.Load() != 1 || body != "upstream" || resp.Header.Get("Age") != "5" {
+		t.Fatalf("calls=%d body=%q age=%q", calls.Load(), body, resp.Header.Get("Age"))
+	}
+
+	resp, body = fetch(t, c, "HEAD", srv.URL+"/a")
+	if calls.Load() != 1 || body != "" || resp.StatusCode != 200 {
+		t.Fatalf("HEAD not served from cache: calls=%d body=%q", calls.Load(), body)
+	}
+
+	clock.advance(time.Minute)
+	fetch(t, c, "GET", srv.URL+"/a")
+	if calls.Load() != 2 {
+		t.Fatalf("expired entry served from cache")
+	}
+}
+
+func TestTransportBypass(t *testing.T) {
+	c, srv, calls, _ := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nostore" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Write([]byte("x"))
+	})
+
+	fetch(t, c, "GET", srv.URL+"/nostore")
+	fetch(t, c, "GET", srv.URL+"/nostore")
+	fetch(t, c, "POST", srv.URL+"/post")
+	fetch(t, c, "POST", srv.URL+"/post")
+	fetch(t, c, "GET", srv.URL+"/auth", "Authorization", "Bearer t")
+	fetch(t, c, "GET", srv.URL+"/auth", "Authorization", "Bearer t")
+	if calls.Load() != 6 {
+		t.Fatalf("uncacheable requests were cached: %d upstream calls", calls.Load())
+	}
+}
+
+func TestTransportRevalidates(t *testing.T) {
+	var conditional atomic.Int64
+	c, srv, calls, clock := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				conditional.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/lm":
+			w.Header().Set("Last-Modified", "Thu, 01 Jan 2026 00:00:00 GMT")
+			if r.Header.Get("If-Modified-Since") != "" {
+				conditional.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Write([]byte("payload"))
+	})
+
+	for _, path := range []string{"/etag", "/lm"} {
+		fetch(t, c, "GET", srv.URL+path)
+		clock.advance(20 * time.Second)
+		resp, body := fetch(t, c, "GET", srv.URL+path)
+		if resp.StatusCode != 200 || body != "payload" {
+			t.Fatalf("%s: revalidated response %d %q", path, resp.StatusCode, body)
+		}
+		// The 304 renewed the freshness lifetime.
+		clock.advance(5 * time.Second)
+		fetch(t, c, "GET", srv.URL+path)
+	}
+	if calls.Load() != 4 || conditional.Load() != 2 {
+		t.Fatalf("calls=%d conditional=%d", calls.Load(), conditional.Load())
+	}
+}
+
+func TestTransportNoCacheAlwaysRevalidates(t *testing.T) {
+	c, srv, calls, _ := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("payload"))
+	})
+
+	fetch(t, c, "GET", srv.URL+"/nc")
+	_, body := fetch(t, c, "GET", srv.URL+"/nc")
+	if calls.Load() != 2 || body != "payload" {
+		t.Fatalf("calls=%d body=%q", calls.Load(), body)
+	}
+}
+
+func TestTransportCoalescesConcurrentMisses(t *testing.T) {
+	release := make(chan struct{})
+	c, srv, calls, _ := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("shared"))
+	})
+	tr := c.Transport.(*Transport)
+
+	const n = 8
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, bodies[i] = fetch(t, c, "GET", srv.URL+"/slow")
+		}(i)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/slow", nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for tr.flights.waiting(primaryKey(req)) < n-1 {
+		if time.Now().After(deadline) {
+			t.Fatal("requests never coalesced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("upstream called %d times", calls.Load())
+	}
+	for i, b := range bodies {
+		if b != "shared" {
+			t.Fatalf("caller %d got %q", i, b)
+		}
+	}
+}
+
+func TestTransportLeaderCancelDoesNotFailFollowers(t *testing.T) {
+	var served atomic.Int64
+	c, srv, calls, _ := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		if served.Add(1) == 1 {
+			<-r.Context().Done() // hold the leader's request until it is cancelled
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("shared"))
+	})
+	tr := c.Transport.(*Transport)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/slow", nil)
+		resp, err := c.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		leaderErr <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("leader never reached upstream")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	const n = 4
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, bodies[i] = fetch(t, c, "GET", srv.URL+"/slow")
+		}(i)
+	}
+	req, _ := http.NewRequest("GET", srv.URL+"/slow", nil)
+	for tr.flights.waiting(primaryKey(req)) < n {
+		if time.Now().After(deadline) {
+			t.Fatal("requests never coalesced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	if err := <-leaderErr; err == nil {
+		t.Fatal("cancelled leader succeeded")
+	}
+	for i, b := range bodies {
+		if b != "shared" {
+			t.Fatalf("follower %d got %q", i, b)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("upstream called %d times, want 2", calls.Load())
+	}
+}
+
+func TestTransportPrivateResponsesNotCoalesced(t *testing.T) {
+	release := make(chan struct{})
+	c, srv, calls, _ := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		w.Write([]byte("secret of " + r.Header.Get("Cookie")))
+	})
+	tr := c.Transport.(*Transport)
+
+	users := []string{"user=alice", "user=bob", "user=carol"}
+	var wg sync.WaitGroup
+	bodies := make([]string, len(users))
+	for i, cookie := range users {
+		wg.Add(1)
+		go func(i int, cookie string) {
+			defer wg.Done()
+			_, bodies[i] = fetch(t, c, "GET", srv.URL+"/me", "Cookie", cookie)
+		}(i, cookie)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/me", nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for tr.flights.waiting(primaryKey(req)) < len(users)-1 {
+		if time.Now().After(deadline) {
+			t.Fatal("requests never coalesced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	for i, cookie := range users {
+		if want := "secret of " + cookie; bodies[i] != want {
+			t.Fatalf("request with %s got %q", cookie, bodies[i])
+		}
+	}
+	if calls.Load() != int64(len(users)) {
+		t.Fatalf("upstream called %d times, want %d", calls.Load(), len(users))
+	}
+}
+
+func TestTransportVary(t *testing.T) {
+	c, srv, calls, _ := newTestTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	})
+
+	fetch(t, c, "GET", srv.URL+"/v", "Accept-Language", "en")
+	fetch(t, c, "GET", srv.URL+"/v", "Accept-Language", "de")
+	_, en := fetch(t, c, "GET", srv.URL+"/v", "Accept-Language", "en")
+	_, de := fetch(t, c, "GET", srv.URL+"/v", "Accept-Language", "de")
+	if calls.Load() != 2 || en != "en" || de != "de" {
+		t.Fatalf("calls=%d en=%q de=%q", calls.Load(), en, de)
+	}
+}
+
+// waiting returns how many callers are waiting on the flight for key.
+func (g *flightGroup) waiting(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.m[key]; ok {
+		return f.waiters
+	}
+	return 0
+}
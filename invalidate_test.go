@@ -0,0 +1,58 @@
+package liteLRU
+
+import (
+	"fmt"
+	"math/bits"
+	"testing"
+)
+
+func TestInvalidateAll(t *testing.T) {
+	c := NewLRUCache(64, 10)
+	defer c.Close()
+
+	for i := 0; i < 64; i++ {
+		c.Add("GET", fmt.Sprintf("/r/%d", i), nil, nil)
+	}
+	c.InvalidateAll()
+	for i := 0; i < 64; i++ {
+		if _, _, ok := c.Get("GET", fmt.Sprintf("/r/%d", i), nil); ok {
+			t.Fatalf("entry %d survived InvalidateAll", i)
+		}
+	}
+
+	// The first insert after the bump reconciles the set, turning every stale
+	// slot into an empty one rather than evicting by CLOCK order.
+	c.Add("GET", "/fresh", nil, nil)
+	if n := bits.OnesCount64(c.chunks[0].valid.Load()); n != 1 {
+		t.Fatalf("expected 1 valid slot after reconcile, got %d", n)
+	}
+	if _, _, ok := c.Get("GET", "/fresh", nil); !ok {
+		t.Fatal("entry added after InvalidateAll missing")
+	}
+
+	// Re-adding an invalidated key must not resurrect its old slot.
+	c.Add("GET", "/r/3", nil, []Param{{Key: "v", Value: "2"}})
+	if _, params, ok := c.Get("GET", "/r/3", nil); !ok || params[0].Value != "2" {
+		t.Fatalf("re-added entry wrong: %v %+v", ok, params)
+	}
+}
+
+func TestClearKeepsSeqlocksMonotonic(t *testing.T) {
+	c := NewLRUCache(64, 10)
+	defer c.Close()
+
+	c.Add("GET", "/a", nil, nil)
+	var before [64]uint32
+	for i := range before {
+		before[i] = c.state(uint32(i)).seq.Load()
+	}
+	c.Clear()
+	for i := range before {
+		if after := c.state(uint32(i)).seq.Load(); after <= before[i] || after%2 != 0 {
+			t.Fatalf("slot %d seqlock went %d -> %d", i, before[i], after)
+		}
+	}
+	if _, _, ok := c.Get("GET", "/a", nil); ok {
+		t.Fatal("entry survived Clear")
+	}
+}
@@ -0,0 +1,52 @@
+package liteLRU
+
+// MaxKeyExtra is the number of dimensions a Key can add to method and path.
+const MaxKeyExtra = 2
+
+// Key is a composite cache key: a method and path plus up to MaxKeyExtra
+// further dimensions, such as a virtual host or an API version. Each part is
+// hashed and compared on its own, so building a Key never concatenates
+// strings. Trailing empty Extra parts are the same as absent ones, and a Key
+// without Extra parts addresses the same entry as Add and Get with its method
+// and path.
+type Key struct {
+	Method string
+	Path   string
+	Extra  [MaxKeyExtra]string
+}
+
+// WithKeyExtras reserves n extra key parts per slot, stored as additional SoA
+// string arrays next to methods and paths, so that Keys with up to n non-empty
+// Extra parts can be cached. It costs 16 bytes of GC-visible memory per slot
+// and part. In arena and compact mode the parts are encoded in the entry
+// record instead and the option is not needed. n is capped at MaxKeyExtra.
+func WithKeyExtras(n int) Option {
+	return func(cfg *config) {
+		cfg.keyExtras = n
+	}
+}
+
+// AddKey adds or updates the entry for a composite key. Keys with more
+// non-empty Extra parts than the cache has room for are not cached and count
+// as drops.
+func (c *LRUCache) AddKey(k Key, handler HandlerFunc, params []Param) {
+	c.add(k.Method, k.Path, handler, params, &entryMeta{extra: &k.Extra})
+}
+
+// GetKey retrieves the entry for a composite key; see Get.
+func (c *LRUCache) GetKey(k Key, dst []Param) (HandlerFunc, []Param, bool) {
+	handler, params, _, res := c.get(k.Method, k.Path, &k.Extra, dst, nil)
+	return handler, params, res == Hit
+}
+
+// extraCount returns the number of extra parts up to the last non-empty one.
+func extraCount(extra *[MaxKeyExtra]string) int {
+	if extra == nil {
+		return 0
+	}
+	n := MaxKeyExtra
+	for n > 0 && extra[n-1] == "" {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompositeKeys(t *testing.T) {
+	for _, opts := range [][]Option{{WithKeyExtras(2)}, {WithArena(0)}, {WithCompactLayout()}} {
+		c := NewLRUCache(1024, 10, opts...)
+
+		keys := []Key{
+			{Method: "GET", Path: "/users"},
+			{Method: "GET", Path: "/users", Extra: [MaxKeyExtra]string{"a.example"}},
+			{Method: "GET", Path: "/users", Extra: [MaxKeyExtra]string{"b.example"}},
+			{Method: "GET", Path: "/users", Extra: [MaxKeyExtra]string{"a.example", "v2"}},
+			{Method: "GET", Path: "/users", Extra: [MaxKeyExtra]string{"", "v2"}},
+			// Parts must not run into each other.
+			{Method: "GET", Path: "/users", Extra: [MaxKeyExtra]string{"a.examplev2"}},
+		}
+		for i, k := range keys {
+			c.AddKey(k, nil, []Param{{Key: "i", Value: fmt.Sprint(i)}})
+		}
+		for i, k := range keys {
+			if _, p, ok := c.GetKey(k, nil); !ok || p[0].Value != fmt.Sprint(i) {
+				t.Fatalf("GetKey(%+v) = %v %+v", k, ok, p)
+			}
+		}
+
+		// A Key without extras is the plain method/path entry.
+		if _, p, ok := c.Get("GET", "/users", nil); !ok || p[0].Value != "0" {
+			t.Fatalf("Get = %v %+v", ok, p)
+		}
+		if got := c.Methods("/users"); len(got) != 1 {
+			t.Fatalf("Methods saw composite entries: %v", got)
+		}
+		c.Close()
+	}
+}
+
+func TestCompositeKeysNeedRoom(t *testing.T) {
+	c := NewLRUCache(256, 10, WithKeyExtras(1))
+	defer c.Close()
+
+	c.AddKey(Key{Method: "GET", Path: "/x", Extra: [MaxKeyExtra]string{"host", "v1"}}, nil, nil)
+	if _, _, drops, _ := c.Stats(); drops != 1 {
+		t.Fatalf("drops = %d", drops)
+	}
+	c.AddKey(Key{Method: "GET", Path: "/x", Extra: [MaxKeyExtra]string{"host"}}, nil, nil)
+	if _, _, ok := c.GetKey(Key{Method: "GET", Path: "/x", Extra: [MaxKeyExtra]string{"host"}}, nil); !ok {
+		t.Fatal("one extra part should fit")
+	}
+}
+
+func TestGetKeyDoesNotAllocate(t *testing.T) {
+	c := NewLRUCache(256, 10, WithKeyExtras(2))
+	defer c.Close()
+
+	k := Key{Method: "GET", Path: "/users/42", Extra: [MaxKeyExtra]string{"api.example", "v3"}}
+	c.AddKey(k, nil, []Param{{Key: "id", Value: "42"}})
+	dst := make([]Param, 0, 4)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, ok := c.GetKey(k, dst); !ok {
+			t.Fatal("miss")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("GetKey allocated %v times", allocs)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"math/bits"
 	"runtime"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/xDarkicex/memory"
@@ -22,22 +23,6 @@ type Param struct {
 // HandlerFunc represents a handler function to be executed when a cached route is matched.
 type HandlerFunc func()
 
-// hashRoute mixes the method and path into a fast 64-bit non-cryptographic hash (FNV-1a variant).
-func hashRoute(method, path string) uint64 {
-	hash := uint64(14695981039346656037)
-	for i := 0; i < len(method); i++ {
-		hash ^= uint64(method[i])
-		hash *= 1099511628211
-	}
-	hash ^= uint64('/')
-	hash *= 1099511628211
-	for i := 0; i < len(path); i++ {
-		hash ^= uint64(path[i])
-		hash *= 1099511628211
-	}
-	return hash
-}
-
 func hasByteSWAR(word uint64, b uint8) bool {
 	pattern := uint64(b) * 0x0101010101010101
 	v := word ^ pattern
@@ -45,30 +30,44 @@ func hasByteSWAR(word uint64, b uint8) bool {
 }
 
 // chunk represents a group of 64 slots with bitmasks for O(1) eviction logic.
-// Padded to 128 bytes (2 cache lines) to prevent false sharing.
+// Padded to 256 bytes (4 cache lines) to prevent false sharing.
 type chunk struct {
 	valid    atomic.Uint64
 	accessed atomic.Uint64
 	writing  atomic.Uint64
-	_        [8]byte
-	sigs     [8]atomic.Uint64 // 64 8-bit hash signatures (1 per slot)
-	_        [32]byte         // pad to 128 bytes total
+	gen      atomic.Uint32 // cache generation the valid bits were last reconciled with
+	_        [4]byte
+	sigs     [8]atomic.Uint64 // 64 8-bit route hash signatures (1 per slot)
+	psigs    [8]atomic.Uint64 // 64 8-bit path-only hash signatures, for Methods
+	sigs2    [8]atomic.Uint64 // second route signature byte (With16BitSignatures)
+	_        [32]byte         // pad to 256 bytes total
 }
 
-// slotState holds the seqlock, padded to a full cache line
-// to completely eliminate false-sharing during concurrent writes.
+// slotState holds the seqlock of a slot. By default each one is laid out on
+// its own cache line, of the size detected at construction, to completely
+// eliminate false-sharing during concurrent writes; WithCompactLayout packs
+// them densely instead. Per-slot metadata shares the otherwise unused rest of
+// the line, so it costs no extra memory.
 type slotState struct {
-	seq atomic.Uint32
-	_   [CacheLineSize - 4]byte
+	seq      atomic.Uint32
+	gen      atomic.Uint32          // cache generation the entry was written in
+	deadline atomic.Int64           // soft freshness deadline, low bit = refresh in flight; 0 = none
+	tags     [MaxTags]atomic.Uint32 // hashes of the entry's tags, 0 = unused
+	ns       atomic.Uint32          // owning namespace id, 0 = none
+	status   atomic.Uint32          // HTTP status of a negative entry (deadline is then its expiry), 0 = positive
+	ref      atomic.Uint64          // arena record reference (arena mode only); last, so compact heap mode can drop it
 }
 
 // statStripe shards cache statistics across independent cache lines
 // to prevent global atomic contention during high-throughput parallel access.
+// Stripes are padded to the detected line size by statStripes.
 type statStripe struct {
-	hits   atomic.Int64
-	misses atomic.Int64
-	drops  atomic.Int64
-	_      [CacheLineSize - 24]byte
+	hits        atomic.Int64
+	misses      atomic.Int64
+	drops       atomic.Int64
+	negHits     atomic.Int64 // lookups answered by an unexpired negative entry
+	negExpired  atomic.Int64 // lookups that found an expired negative entry
+	falseProbes atomic.Int64 // signature matches whose key did not match
 }
 
 //go:nosplit
@@ -140,32 +139,60 @@ func (a *atomicHandler) Load() HandlerFunc {
 	return *(*HandlerFunc)(unsafe.Pointer(&ptr))
 }
 
-// LRUCache implements an ultra-low latency lock-free 64-way set associative cache.
-// It uses a Chunked Bitmask CLOCK algorithm for O(1) eviction within each set,
-// SIMD/SWAR byte scanning for O(1) lookups without a hash map, and off-heap
-// mmap-backed SoA arrays that are invisible to the Go garbage collector.
+// LRUCache implements an ultra-low latency lock-free set associative cache,
+// 64-way unless WithWays selects otherwise. It uses a Chunked Bitmask CLOCK
+// algorithm for O(1) eviction within each set, SIMD/SWAR byte scanning for
+// O(1) lookups without a hash map, and off-heap mmap-backed SoA arrays that
+// are invisible to the Go garbage collector.
 type LRUCache struct {
 	capacity  uint32
 	maxParams int
 
 	// Structure of Arrays (SoA) allocated on the Go heap so the GC can safely manage
-	// dynamic strings and slice pointers.
+	// dynamic strings and slice pointers. In arena and compact mode methods, paths,
+	// params and extras are nil and each entry is one encoded record instead, in
+	// the off-heap arena or behind records.
 	methods  []atomicString
 	paths    []atomicString
-	handlers []atomicHandler
+	extras   [][]atomicString // one array per extra key part (WithKeyExtras)
+	handlers []atomicHandler  // nil in shared caches, which store handler names in records
 	params   []atomicSlice
+	records  []atomic.Pointer[byte] // immutable heap records (WithCompactLayout without WithArena)
+	values   []atomic.Pointer[any]  // opaque values (nil unless WithValues)
 
 	// Concurrency control structures (no pointers), backed by off-heap mmap memory.
 	// This avoids GC write barriers and scanning overhead during dense bitmask/seqlock operations.
-	states []slotState // padded seqlocks to prevent read-tearing
-	chunks []chunk     // padded bitmasks and SWAR signatures (64 slots per chunk)
+	states  []byte        // seqlocks to prevent read-tearing, stride bytes apart (see state)
+	stride  uintptr       // distance between slotStates in states
+	chunks  []chunk       // padded bitmasks and SWAR signatures (64 slots per chunk)
+	arena   *keyArena     // off-heap entry records (nil unless WithArena)
+	loader  *loader       // background refresher (nil unless WithLoader)
+	victims *victimBuffer // recently evicted entries (nil unless WithVictimBuffer)
+
+	now         func() int64 // monotonic nanoseconds since creation (wall clock since file creation if shared)
+	negativeTTL int64
+	normalize   KeyNormalizer   // nil unless WithKeyNormalizer
+	registry    HandlerRegistry // resolves handler names of a shared cache
 
 	// Raw mmap slabs — held for Munmap on Close()
 	statesSlab []byte
 	chunksSlab []byte
-
-	numGroups uint32
-	stats     [64]statStripe
+	mmap       MmapStatus     // outcome of the mmap tuning options
+	shared     *sharedMapping // file holding states, chunks and arena instead (nil unless OpenShared)
+
+	line      uintptr // coherence-line size states and stats are padded to
+	numGroups uint32  // number of 64-slot chunks
+	numSets   uint32  // number of sets of ways slots each
+	ways      uint32
+	effWays   atomic.Uint32                // ways inserts may fill, below ways under memory pressure
+	watch     *pressureWatch               // nil unless WithMemoryPressure
+	fnv       bool                         // FNV-1a key hash (WithFNVHash)
+	sig16     bool                         // second signature plane in use (With16BitSignatures)
+	twoChoice bool                         // keys may live in an alternate set (WithTwoChoice)
+	gen       *atomic.Uint32               // bumped by InvalidateAll; older slots read as empty (in the file header if shared)
+	spaces    atomic.Pointer[[]*Namespace] // indexed by namespace id - 1
+	stats     statStripes                  // 64 stripes
+	epoch     epochDomain                  // pins held by View borrowers
 }
 
 func nextPowerOfTwo(n int) int {
@@ -200,49 +227,183 @@ func mmapSlice[T any](n int) ([]T, []byte) {
 // backed by off-heap mmap memory. The SoA arrays are invisible to the Go GC —
 // no write barriers, no mark-phase scanning, no GC-induced tail latency.
 // Call Close() to release the mmap slabs when the cache is no longer needed.
-func NewLRUCache(capacity, maxParams int) *LRUCache {
+func NewLRUCache(capacity, maxParams int, opts ...Option) *LRUCache {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	c, _ := newLRUCache(capacity, maxParams, &cfg)
+	return c
+}
+
+// newLRUCache builds a cache from applied options. It only fails for shared
+// caches (see OpenShared), whose file may be unusable.
+func newLRUCache(capacity, maxParams int, cfg *config) (*LRUCache, error) {
 	if capacity <= 0 {
 		capacity = 1024
 	}
 	capacity = nextPowerOfTwo(capacity)
-	if capacity < 64 {
-		capacity = 64
-	}
-
 	if maxParams <= 0 {
 		maxParams = 10
 	}
+	if cfg.ways == 0 {
+		cfg.ways = 64
+	}
+	if capacity < max(64, cfg.ways) {
+		capacity = max(64, cfg.ways)
+	}
 
 	numGroups := uint32(capacity / 64)
 
-	methods := make([]atomicString, capacity)
-	paths := make([]atomicString, capacity)
-	handlers := make([]atomicHandler, capacity)
-	params := make([]atomicSlice, capacity)
+	line := uintptr(cfg.lineSize)
+	if line == 0 {
+		line = uintptr(hostLineSize())
+	}
+	stride := padTo(unsafe.Sizeof(slotState{}), line)
+	if cfg.compact {
+		stride = unsafe.Sizeof(slotState{})
+		if !cfg.arena && cfg.shared == nil {
+			stride = unsafe.Offsetof(slotState{}.ref) // no arena, no ref
+		}
+	}
+	if cfg.arena && cfg.arenaBytes <= 0 {
+		cfg.arenaBytes = capacity * 256
+	}
+
+	var (
+		methods, paths []atomicString
+		extras         [][]atomicString
+		params         []atomicSlice
+		records        []atomic.Pointer[byte]
+		handlers       []atomicHandler
+		arena          *keyArena
+		shared         *sharedMapping
+	)
+	switch {
+	case cfg.shared != nil:
+		granules, _ := arenaSize(cfg.arenaBytes)
+		var err error
+		shared, err = mapShared(cfg.shared.path, sharedLayout{
+			capacity: uint32(capacity),
+			ways:     uint32(cfg.ways),
+			stride:   uint32(stride),
+			granules: uint64(granules),
+			flags:    cfg.sharedFlags(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		arena = shared.arena
+	case cfg.arena:
+		arena = newKeyArena(cfg.arenaBytes)
+	case cfg.compact:
+		records = make([]atomic.Pointer[byte], capacity)
+	default:
+		methods = make([]atomicString, capacity)
+		paths = make([]atomicString, capacity)
+		params = make([]atomicSlice, capacity)
+		for i := 0; i < min(cfg.keyExtras, MaxKeyExtra); i++ {
+			extras = append(extras, make([]atomicString, capacity))
+		}
+	}
+	if shared == nil {
+		handlers = make([]atomicHandler, capacity)
+	}
+	var values []atomic.Pointer[any]
+	if cfg.values {
+		values = make([]atomic.Pointer[any], capacity)
+	}
 
-	states, statesSlab := mmapSlice[slotState](capacity)
-	chunks, chunksSlab := mmapSlice[chunk](int(numGroups))
+	if cfg.negativeTTL <= 0 {
+		cfg.negativeTTL = 5 * time.Second
+	}
+	base := time.Now()
+	now := func() int64 { return int64(time.Since(base)) }
+	gen := new(atomic.Uint32)
+
+	var (
+		states, statesSlab, chunksSlab []byte
+		chunks                         []chunk
+		slabs                          [][]byte
+	)
+	if shared != nil {
+		// Deadlines are shared with other processes, so they count from the
+		// wall-clock time the file was created instead.
+		created := shared.hdr.created
+		now = func() int64 { return time.Now().UnixNano() - created }
+		gen = &shared.hdr.gen
+		states, chunks = shared.states, shared.chunks
+		slabs = [][]byte{shared.mem}
+	} else {
+		// A stride that omits ref leaves the last slotState short of it.
+		slack := int(unsafe.Sizeof(slotState{})) - min(int(stride), int(unsafe.Sizeof(slotState{})))
+		states, statesSlab = mmapSlice[byte](capacity*int(stride) + slack)
+		chunks, chunksSlab = mmapSlice[chunk](int(numGroups))
+		slabs = [][]byte{statesSlab, chunksSlab}
+		if arena != nil {
+			slabs = append(slabs, arena.slab)
+		}
+	}
 
-	return &LRUCache{
-		capacity:   uint32(capacity),
-		maxParams:  maxParams,
-		methods:    methods,
-		paths:      paths,
-		handlers:   handlers,
-		params:     params,
-		states:     states,
-		chunks:     chunks,
-		statesSlab: statesSlab,
-		chunksSlab: chunksSlab,
-		numGroups:  numGroups,
+	c := &LRUCache{
+		capacity:    uint32(capacity),
+		maxParams:   maxParams,
+		methods:     methods,
+		paths:       paths,
+		extras:      extras,
+		handlers:    handlers,
+		params:      params,
+		records:     records,
+		values:      values,
+		states:      states,
+		stride:      stride,
+		line:        line,
+		stats:       newStatStripes(64, line),
+		epoch:       newEpochDomain(line),
+		chunks:      chunks,
+		arena:       arena,
+		loader:      newLoader(cfg),
+		now:         now,
+		negativeTTL: int64(cfg.negativeTTL),
+		normalize:   chainNormalizers(cfg.normalizers),
+		statesSlab:  statesSlab,
+		chunksSlab:  chunksSlab,
+		shared:      shared,
+		mmap:        tuneSlabs(cfg, slabs...),
+		numGroups:   numGroups,
+		numSets:     uint32(capacity / cfg.ways),
+		ways:        uint32(cfg.ways),
+		twoChoice:   cfg.twoChoice,
+		sig16:       cfg.sig16,
+		fnv:         cfg.fnv,
+		gen:         gen,
+		victims:     newVictimBuffer(cfg.victims),
+	}
+	c.effWays.Store(c.ways)
+	if cfg.pressure != nil {
+		c.watchPressure(*cfg.pressure)
 	}
+	return c, nil
 }
 
 // Close releases all off-heap mmap slabs. The cache must not be used after Close.
+// A shared cache is unmapped, but its file and the entries in it remain. Close
+// first waits for background refreshes (see WithLoader) that are in flight.
 func (c *LRUCache) Close() {
-	for _, slab := range [][]byte{
-		c.statesSlab, c.chunksSlab,
-	} {
+	c.loader.close()
+	if c.watch != nil {
+		close(c.watch.stop)
+		<-c.watch.done
+	}
+	if c.shared != nil {
+		c.shared.close()
+		return
+	}
+	slabs := [][]byte{c.statesSlab, c.chunksSlab}
+	if c.arena != nil {
+		slabs = append(slabs, c.arena.slab)
+	}
+	for _, slab := range slabs {
 		if slab != nil {
 			memory.Munmap(slab)
 		}
@@ -250,154 +411,523 @@ func (c *LRUCache) Close() {
 }
 
 // findVictim uses bitwise operations to instantly find an eviction victim in O(1) time
-// within a specific 64-slot set (group).
+// within a specific set (group). Empty slots anywhere in the set are preferred over
+// valid but not accessed ones.
 func (c *LRUCache) findVictim(group uint32) uint32 {
-	chk := &c.chunks[group]
+	first, n, mask := c.usable(group)
 	retries := 0
 
+	gen := c.gen.Load()
+	for k := first; k < first+n; k++ {
+		if chk := &c.chunks[k]; chk.gen.Load() != gen {
+			c.reconcile(chk, k, gen)
+		}
+	}
+
+retry:
 	for {
-		validBits := chk.valid.Load()
-		accessedBits := chk.accessed.Load()
-		writingBits := chk.writing.Load()
+		// Candidates: empty slots first (including those emptied by InvalidateAll),
+		// then valid but not accessed. Currently writing slots are excluded.
+		contended := false
+		for pass := 0; pass < 2; pass++ {
+			for k := first; k < first+n; k++ {
+				chk := &c.chunks[k]
+				validBits := chk.valid.Load()
+				accessedBits := chk.accessed.Load()
+				writingBits := chk.writing.Load()
+				contended = contended || writingBits&mask != 0
+
+				candidates := ^validBits & ^writingBits & mask
+				if pass == 1 {
+					candidates = validBits & ^accessedBits & ^writingBits & mask
+				}
+				if candidates == 0 {
+					continue
+				}
 
-		// Candidates: not valid (empty), or valid but not accessed
-		candidates := ^validBits | (validBits & ^accessedBits)
-		// Exclude currently writing slots
-		candidates &= ^writingBits
+				bit := uint32(bits.TrailingZeros64(candidates))
 
-		if candidates != 0 {
-			bit := uint32(bits.TrailingZeros64(candidates))
+				// Attempt to claim this bit for writing
+				if chk.writing.CompareAndSwap(writingBits, writingBits|(1<<bit)) {
+					return k*64 + bit
+				}
 
-			// Attempt to claim this bit for writing
-			if chk.writing.CompareAndSwap(writingBits, writingBits|(1<<bit)) {
-				return group*64 + bit
-			}
-			
-			retries++
-			if retries > 10 {
-				return 0xFFFFFFFF // Shed load to guarantee bounded execution
+				retries++
+				if retries > 10 {
+					return 0xFFFFFFFF // Shed load to guarantee bounded execution
+				}
+				continue retry // CAS failed, rescan the set
 			}
-			continue // CAS failed, retry
 		}
 
-		// No candidates available in this chunk.
+		// No candidates available in this set.
 		// Clear the accessed bits of currently valid items to give them a second chance,
 		// while preserving any concurrent access bits set by readers.
-		chk.accessed.And(^validBits)
-		
+		for k := first; k < first+n; k++ {
+			chk := &c.chunks[k]
+			chk.accessed.And(^(chk.valid.Load() & mask))
+		}
+
 		retries++
 		if retries > 10 {
 			return 0xFFFFFFFF // Shed load to guarantee bounded execution
 		}
-		
+
 		// If we failed to find a candidate because other threads are currently writing,
 		// yield the processor. This prevents a spin-lock preemption meltdown (priority inversion)
 		// under massive concurrent thundering herds.
-		if writingBits != 0 {
+		if contended {
 			runtime.Gosched()
 		}
 	}
 }
 
-// Add adds a new entry to the cache or updates an existing one.
-func (c *LRUCache) Add(method, path string, handler HandlerFunc, params []Param) {
-	hash := hashRoute(method, path)
-	group := uint32(hash % uint64(c.numGroups))
-	stripeIdx := hash & 63
-	chk := &c.chunks[group]
+// claimSlot sets the writing bit of one slot, failing if another writer holds it.
+func claimSlot(chk *chunk, bit uint32) bool {
+	for {
+		w := chk.writing.Load()
+		if w&(1<<bit) != 0 {
+			return false
+		}
+		if chk.writing.CompareAndSwap(w, w|(1<<bit)) {
+			return true
+		}
+	}
+}
 
-	sig8 := uint8(hash >> 32)
-	if sig8 == 0 {
-		sig8 = 1
+// releaseSlot clears the writing bit of one slot.
+func releaseSlot(chk *chunk, bit uint32) {
+	for {
+		w := chk.writing.Load()
+		if chk.writing.CompareAndSwap(w, w & ^(1<<bit)) {
+			break
+		}
 	}
+}
 
-	// 1. Try to find and update an existing entry
-	for i := uint32(0); i < 8; i++ {
-		word := chk.sigs[i].Load()
-		if hasByteSWAR(word, sig8) {
-			for j := uint32(0); j < 8; j++ {
-				if byte((word>>(j*8))&0xFF) == sig8 {
-					idx := group*64 + i*8 + j
-
-					validBits := chk.valid.Load()
-					if (validBits & (1 << (i*8 + j))) == 0 {
-						continue
-					}
+// keyEquals reports whether slot idx currently holds method, path and the
+// extra key parts (nil for none). Callers validate the answer with the slot
+// seqlock.
+func (c *LRUCache) keyEquals(idx uint32, method, path string, extra *[MaxKeyExtra]string) bool {
+	if c.methods == nil {
+		b := c.record(idx)
+		return b != nil && recordMatches(b, method, path, extra)
+	}
+	if c.methods[idx].Load() != method || c.paths[idx].Load() != path {
+		return false
+	}
+	for i := range c.extras {
+		want := ""
+		if extra != nil {
+			want = extra[i]
+		}
+		if c.extras[i][idx].Load() != want {
+			return false
+		}
+	}
+	return true
+}
 
-					// Verify lock-free
-					if c.methods[idx].Load() == method && c.paths[idx].Load() == path {
-						// Found it! Try to lock and overwrite.
-						seq := c.states[idx].seq.Load()
-						if seq%2 != 0 || !c.states[idx].seq.CompareAndSwap(seq, seq+1) {
-							c.stats[stripeIdx].drops.Add(1)
-							return // Someone else is updating it, drop our redundant update
-						}
-
-						c.handlers[idx].Store(handler)
-						oldParams := c.params[idx].Load()
-						var newParams []Param
-						if len(params) > 0 {
-							if cap(oldParams) >= len(params) {
-								newParams = oldParams[:len(params)]
-								copy(newParams, params)
-							} else {
-								newParams = make([]Param, len(params))
-								copy(newParams, params)
-							}
-						}
-						c.params[idx].Store(newParams)
-
-						c.states[idx].seq.Store(seq + 2)
-						return
-					}
-				}
-			}
+// loadParams copies the params of slot idx into dst, allocating only if dst
+// is too small. ok is false if a concurrent rewrite was observed mid-decode.
+// In arena mode the param bytes are copied out as well, in one allocation,
+// because the block they were decoded from is recycled once the entry goes.
+func (c *LRUCache) loadParams(idx uint32, dst []Param) (copiedParams []Param, ok bool) {
+	if c.methods == nil {
+		b := c.record(idx)
+		if b == nil {
+			return nil, false
+		}
+		copiedParams, ok = recordParams(b, dst)
+		if ok && c.arena != nil {
+			detachParams(copiedParams)
 		}
+		return copiedParams, ok
 	}
+	params := c.params[idx].Load()
+	if len(params) > 0 {
+		if cap(dst) >= len(params) {
+			copiedParams = dst[:len(params)]
+		} else {
+			copiedParams = make([]Param, len(params))
+		}
+		copy(copiedParams, params)
+	}
+	return copiedParams, true
+}
 
-	// 2. Not found, we need to evict a victim from this 64-slot set
-	victimIdx := c.findVictim(group)
-	if victimIdx == 0xFFFFFFFF {
-		c.stats[stripeIdx].drops.Add(1)
-		return // Load shedding: chunk is highly contended, skip cache insertion
+// entryMeta carries the per-slot metadata stamped alongside an entry.
+type entryMeta struct {
+	tags   [MaxTags]uint32
+	ns     uint32
+	status uint32               // nonzero for negative entries
+	extra  *[MaxKeyExtra]string // extra key parts, nil for none
+	name   string               // handler name stored in the record (shared caches only)
+	value  *any                 // opaque value (WithValues only)
+
+	deadline int64 // loader deadline to keep, 0 to start a fresh one
+}
+
+// storeEntry publishes the key, params and metadata of slot idx. The caller
+// must own the slot's writing bit and hold its seqlock odd. ref is the
+// pre-encoded arena record in arena mode; the record it replaces is released
+// to the arena. rec is the pre-encoded heap record in compact mode.
+func (c *LRUCache) storeEntry(idx uint32, method, path string, params []Param, ref uint64, rec *byte, meta *entryMeta) {
+	st := c.state(idx)
+	st.gen.Store(c.gen.Load())
+	for i := range meta.tags {
+		st.tags[i].Store(meta.tags[i])
+	}
+	st.ns.Store(meta.ns)
+	st.status.Store(meta.status)
+	if meta.status != 0 {
+		st.deadline.Store(c.now() + c.negativeTTL)
+	} else if meta.deadline != 0 {
+		st.deadline.Store(meta.deadline)
+	} else {
+		st.deadline.Store(c.loader.deadline(c.now()))
+	}
+	if c.values != nil {
+		c.values[idx].Store(meta.value)
 	}
-	bit := victimIdx % 64
 
-	// We own the writing bit. Set seqlock to odd.
-	seq := c.states[victimIdx].seq.Load()
-	c.states[victimIdx].seq.Store(seq + 1) // odd
+	if c.arena != nil {
+		c.release(idx, c.state(idx).ref.Swap(ref))
+		return
+	}
+	if c.records != nil {
+		c.records[idx].Store(rec)
+		return
+	}
 
-	// Write new data safely under seqlock
-	c.methods[victimIdx].Store(method)
-	c.paths[victimIdx].Store(path)
-	c.handlers[victimIdx].Store(handler)
+	c.methods[idx].Store(method)
+	c.paths[idx].Store(path)
+	for i := range c.extras {
+		part := ""
+		if meta.extra != nil {
+			part = meta.extra[i]
+		}
+		c.extras[i][idx].Store(part)
+	}
 
-	oldParams := c.params[victimIdx].Load()
+	// The old backing array is only recycled in place when no View borrower
+	// can be reading it; otherwise the GC keeps it alive for the borrower.
+	oldParams := c.params[idx].Load()
 	var newParams []Param
 	if len(params) > 0 {
-		if cap(oldParams) >= len(params) {
+		if cap(oldParams) >= len(params) && c.epoch.quiescent(idx) {
 			newParams = oldParams[:len(params)]
 			copy(newParams, params)
 		} else {
 			newParams = make([]Param, len(params))
 			copy(newParams, params)
 		}
+	}
+	c.params[idx].Store(newParams)
+}
+
+// locate derives the set, stat stripe and signature of a route with extra
+// key parts (nil for none) within namespace ns (0 for the shared key space),
+// plus the path-only signature. The low byte of the signature goes into the
+// sigs plane and the high byte into sigs2 (With16BitSignatures only). The set
+// does not depend on the method, so all methods of a path share a set.
+func (c *LRUCache) locate(method, path string, extra *[MaxKeyExtra]string, ns uint32) (group uint32, stripeIdx uint64, sig uint16, psig uint8) {
+	group, pathHash, psig := c.locatePath(path, extra, ns)
+	var hash uint64
+	if c.fnv {
+		hash = fnvRoute(pathHash, method)
 	} else {
-		newParams = nil
+		hash = wordRoute(pathHash, method)
+	}
+	stripeIdx = hash & 63
+	sig = uint16(uint8(hash>>32)) | uint16(uint8(hash>>48))<<8
+	if uint8(sig) == 0 {
+		sig |= 1
+	}
+	return group, stripeIdx, sig, psig
+}
+
+// locatePath derives the set and path-only signature of a path with extra
+// key parts (nil for none) within namespace ns. Extra parts such as a host
+// take part in set selection so that they spread across sets.
+func (c *LRUCache) locatePath(path string, extra *[MaxKeyExtra]string, ns uint32) (group uint32, pathHash uint64, psig uint8) {
+	if c.fnv {
+		pathHash = fnvPath(path)
+		if extra != nil {
+			pathHash = fnvExtra(pathHash, extra)
+		}
+	} else {
+		pathHash = wordPath(path)
+		if extra != nil {
+			pathHash = wordExtra(pathHash, extra)
+		}
+	}
+	if ns != 0 {
+		pathHash ^= uint64(ns) * 0x9E3779B97F4A7C15
+		pathHash *= 1099511628211
+	}
+	group = uint32(pathHash % uint64(c.numSets))
+	psig = uint8(pathHash >> 40)
+	if psig == 0 {
+		psig = 1
+	}
+	return group, pathHash, psig
+}
+
+// span returns the chunks holding set group: n chunks from first, each
+// restricted to the slot bits in mask. A set is ways consecutive slots, so
+// below 64 ways several sets share a chunk and at 128 ways a set spans two.
+func (c *LRUCache) span(group uint32) (first, n uint32, mask uint64) {
+	if c.ways >= 64 {
+		n = c.ways / 64
+		return group * n, n, ^uint64(0)
+	}
+	base := group * c.ways
+	return base / 64, 1, (uint64(1)<<c.ways - 1) << (base % 64)
+}
+
+// lookup scans a set's SWAR signatures for a valid slot holding method/path.
+// It returns the chunk holding the slot, the slot index, its bit within the
+// chunk and the even seqlock value observed before the key comparison, which
+// callers re-check after reading the slot's data. Live candidates of the
+// current generation and namespace whose signature matched but whose key did
+// not are counted as false probes.
+func (c *LRUCache) lookup(group uint32, sig uint16, method, path string, extra *[MaxKeyExtra]string, ns uint32) (chk *chunk, idx, bit, seq uint32, ok bool) {
+	first, n, mask := c.span(group)
+	gen := c.gen.Load()
+	for k := first; k < first+n; k++ {
+		chk = &c.chunks[k]
+		m := matchSigs(&chk.sigs, uint8(sig)) & mask & chk.valid.Load()
+		if c.sig16 && m != 0 {
+			m &= matchSigs(&chk.sigs2, uint8(sig>>8))
+		}
+		for ; m != 0; m &= m - 1 {
+			bit = uint32(bits.TrailingZeros64(m))
+			idx = k*64 + bit
+
+			// Start read seqlock
+			seq = c.state(idx).seq.Load()
+			if seq%2 != 0 {
+				continue // Being written
+			}
+
+			// Entries from before the last InvalidateAll read as empty,
+			// each namespace only sees its own entries, and the key itself
+			// guards against concurrent evictions and signature collisions.
+			// Only the latter count as false probes.
+			if c.state(idx).gen.Load() != gen || c.state(idx).ns.Load() != ns {
+				continue
+			}
+			if !c.keyEquals(idx, method, path, extra) {
+				c.stats.at(uint64(idx & 63)).falseProbes.Add(1)
+				continue
+			}
+			return chk, idx, bit, seq, true
+		}
 	}
-	c.params[victimIdx].Store(newParams)
+	return nil, 0, 0, 0, false
+}
 
-	// Update SWAR signature
+// setSig stores the 8-bit signature of slot bit in a SWAR signature plane.
+func setSig(plane []atomic.Uint64, bit uint32, sig uint8) {
 	sigWordIdx := bit / 8
 	sigByteShift := (bit % 8) * 8
 	for {
-		oldWord := chk.sigs[sigWordIdx].Load()
+		oldWord := plane[sigWordIdx].Load()
 		newWord := oldWord & ^(uint64(0xFF) << sigByteShift)
-		newWord |= (uint64(sig8) << sigByteShift)
-		if chk.sigs[sigWordIdx].CompareAndSwap(oldWord, newWord) {
+		newWord |= (uint64(sig) << sigByteShift)
+		if plane[sigWordIdx].CompareAndSwap(oldWord, newWord) {
+			break
+		}
+	}
+}
+
+// markAccessed sets the CLOCK reference bit of a slot via CAS loop.
+func markAccessed(chk *chunk, bit uint32) {
+	for {
+		acc := chk.accessed.Load()
+		if (acc & (1 << bit)) != 0 {
+			break // already accessed
+		}
+		if chk.accessed.CompareAndSwap(acc, acc|(1<<bit)) {
 			break
 		}
 	}
+}
+
+// release returns an arena record that has just been unlinked from slot idx.
+// Plain Get readers are protected by the seqlock, so the block is reusable at
+// once unless View borrowers are pinned on the slot's stripe, in which case
+// it waits out two epochs. Blocks still parked once the borrowers have gone
+// are drained a bucket per release.
+func (c *LRUCache) release(idx uint32, ref uint64) {
+	if ref == 0 {
+		return
+	}
+	if c.epoch.quiescent(idx) {
+		c.arena.free(ref)
+		if c.arena.parked.Load() == 0 {
+			return
+		}
+	} else {
+		c.arena.retire(ref, c.epoch.global.Load())
+	}
+	if bucket, ok := c.epoch.tryAdvance(); ok {
+		c.arena.reclaim(bucket)
+	}
+}
+
+// reconcile is the lazy half of InvalidateAll: the first writer to touch a
+// chunk after a generation bump clears the valid bits of every slot written in
+// an older generation, so they become preferred eviction victims. It costs one
+// pass over the chunk's valid slots per generation.
+func (c *LRUCache) reconcile(chk *chunk, group, gen uint32) {
+	old := chk.gen.Load()
+	if old == gen || !chk.gen.CompareAndSwap(old, gen) {
+		return
+	}
+	var stale uint64
+	for m := chk.valid.Load(); m != 0; m &= m - 1 {
+		bit := uint32(bits.TrailingZeros64(m))
+		if c.state(group*64+bit).gen.Load() != gen {
+			stale |= 1 << bit
+		}
+	}
+	// Slots being rewritten right now will be stamped with the new generation.
+	stale &= ^chk.writing.Load()
+	chk.valid.And(^stale)
+	chk.accessed.And(^stale)
+	for m := stale; m != 0; m &= m - 1 {
+		c.disown(group*64 + uint32(bits.TrailingZeros64(m)))
+	}
+}
+
+// Add adds a new entry to the cache or updates an existing one.
+func (c *LRUCache) Add(method, path string, handler HandlerFunc, params []Param) {
+	c.add(method, path, handler, params, &entryMeta{})
+}
+
+// add implements Add for every entry flavour; meta is stamped into the slot.
+// It reports whether a valid entry was evicted to make room.
+func (c *LRUCache) add(method, path string, handler HandlerFunc, params []Param, meta *entryMeta) (evicted bool) {
+	method, path = c.normalizeKey(method, path)
+	group, stripeIdx, sig, psig := c.locate(method, path, meta.extra, meta.ns)
+	space := c.namespace(meta.ns)
+
+	// Heap mode has SoA arrays only for the extra parts WithKeyExtras asked for.
+	if c.methods != nil && extraCount(meta.extra) > len(c.extras) {
+		c.drop(stripeIdx, space)
+		return
+	}
+
+	// In arena mode the record is encoded before any slot is claimed, so an
+	// exhausted arena sheds the insert without disturbing the set.
+	var ref uint64
+	if c.arena != nil {
+		if ref = c.arena.put(method, path, meta.extra, meta.name, params); ref == 0 {
+			c.drop(stripeIdx, space)
+			return
+		}
+	}
+	var rec *byte
+	if c.records != nil {
+		if rec = newRecord(method, path, meta.extra, params); rec == nil {
+			c.drop(stripeIdx, space)
+			return
+		}
+	}
+
+	// 1. Try to find and update an existing entry
+	if chk, idx, bit, _, ok := c.probe(group, sig, psig, method, path, meta.extra, meta.ns); ok {
+		// Found it! Claim the writing bit so findVictim cannot pick the slot
+		// while we overwrite it.
+		if !claimSlot(chk, bit) {
+			if c.arena != nil {
+				c.arena.free(ref)
+			}
+			c.drop(stripeIdx, space)
+			return // Someone else is updating it, drop our redundant update
+		}
+
+		// The slot may have been evicted between the lookup and the claim; if so
+		// fall through and insert as a new entry.
+		if chk.valid.Load()&(1<<bit) != 0 && c.keyEquals(idx, method, path, meta.extra) {
+			seq := c.state(idx).seq.Load()
+			c.state(idx).seq.Store(seq + 1) // odd
+
+			c.setHandler(idx, handler)
+			c.storeEntry(idx, method, path, params, ref, rec, meta)
+
+			c.state(idx).seq.Store(seq + 2)
+			releaseSlot(chk, bit)
+			return
+		}
+		releaseSlot(chk, bit)
+	}
+
+	// 2. Not found, we need to evict a victim from this set. A
+	// namespace at its quota evicts one of its own entries instead.
+	group = c.place(group, psig)
+	victimIdx := uint32(0xFFFFFFFF)
+	quota := false
+	if space != nil && space.count.Load() >= space.quota.Load() {
+		var ok bool
+		if victimIdx, ok = space.makeRoom(group); !ok {
+			if c.arena != nil {
+				c.arena.free(ref)
+			}
+			c.drop(stripeIdx, space)
+			return
+		}
+		quota = victimIdx != 0xFFFFFFFF
+	}
+	if victimIdx == 0xFFFFFFFF {
+		victimIdx = c.findVictim(group)
+	}
+	if victimIdx == 0xFFFFFFFF {
+		if c.arena != nil {
+			c.arena.free(ref)
+		}
+		c.drop(stripeIdx, space)
+		return // Load shedding: chunk is highly contended, skip cache insertion
+	}
+	chk := &c.chunks[victimIdx/64]
+	bit := victimIdx % 64
+
+	// We own the writing bit, so the valid bit of the victim is stable. A
+	// CLOCK victim is parked in the victim buffer before it is overwritten,
+	// and any parked copy of the new key is now stale.
+	if chk.valid.Load()&(1<<bit) != 0 {
+		if c.victims != nil && !quota {
+			if v := c.capture(victimIdx); v != nil {
+				c.victims.put(v, uint8(chk.sigs[bit/8].Load()>>(bit%8*8)))
+			}
+		}
+		c.disown(victimIdx)
+		evicted = true
+	}
+	if c.victims != nil {
+		c.victims.forget(uint8(sig), method, path, meta.extra, meta.ns, c.gen.Load())
+	}
+	if space != nil {
+		space.count.Add(1)
+		space.last.Store(victimIdx / 64)
+	}
+
+	// Set seqlock to odd.
+	seq := c.state(victimIdx).seq.Load()
+	c.state(victimIdx).seq.Store(seq + 1) // odd
+
+	// Write new data safely under seqlock
+	c.setHandler(victimIdx, handler)
+	c.storeEntry(victimIdx, method, path, params, ref, rec, meta)
+
+	// Update SWAR signatures
+	setSig(chk.sigs[:], bit, uint8(sig))
+	if c.sig16 {
+		setSig(chk.sigs2[:], bit, uint8(sig>>8))
+	}
+	setSig(chk.psigs[:], bit, psig)
 
 	// Mark as accessed
 	for {
@@ -416,141 +946,179 @@ func (c *LRUCache) Add(method, path string, handler HandlerFunc, params []Param)
 	}
 
 	// Finish write: seq becomes even
-	c.states[victimIdx].seq.Store(seq + 2)
+	c.state(victimIdx).seq.Store(seq + 2)
 
 	// Release writing bit
-	for {
-		w := chk.writing.Load()
-		if chk.writing.CompareAndSwap(w, w & ^(1<<bit)) {
-			break
-		}
-	}
+	releaseSlot(chk, bit)
+	return evicted
 }
 
 // Get retrieves an entry from the cache lock-free, zero allocation.
 // The dst slice is used to avoid heap allocations when copying params.
+// Negative entries (see AddNotFound) are reported as not found; use Lookup
+// to tell them apart from misses.
 func (c *LRUCache) Get(method, path string, dst []Param) (HandlerFunc, []Param, bool) {
-	hash := hashRoute(method, path)
-	group := uint32(hash % uint64(c.numGroups))
-	chk := &c.chunks[group]
-	stripeIdx := hash & 63
-
-	sig8 := uint8(hash >> 32)
-	if sig8 == 0 {
-		sig8 = 1
-	}
-
-	for i := uint32(0); i < 8; i++ {
-		word := chk.sigs[i].Load()
-		if hasByteSWAR(word, sig8) {
-			for j := uint32(0); j < 8; j++ {
-				if byte((word>>(j*8))&0xFF) == sig8 {
-					idx := group*64 + i*8 + j
-
-					validBits := chk.valid.Load()
-					if (validBits & (1 << (i*8 + j))) == 0 {
-						continue
-					}
-
-					// Start read seqlock
-					seq1 := c.states[idx].seq.Load()
-					if seq1%2 != 0 {
-						continue // Being written
-					}
+	handler, params, _, res := c.get(method, path, nil, dst, nil)
+	return handler, params, res == Hit
+}
 
-					// Validate method/path against concurrent evictions and collisions
-					if c.methods[idx].Load() == method && c.paths[idx].Load() == path {
-						// Safely read data
-						handler := c.handlers[idx].Load()
-						params := c.params[idx].Load()
-
-						var copiedParams []Param
-						if len(params) > 0 {
-							if cap(dst) >= len(params) {
-								copiedParams = dst[:len(params)]
-							} else {
-								copiedParams = make([]Param, len(params))
-							}
-							copy(copiedParams, params)
-						}
-
-						// Validate read seqlock
-						seq2 := c.states[idx].seq.Load()
-						if seq1 != seq2 {
-							continue
-						}
-
-						// Mark as accessed for CLOCK via CAS loop
-						bit := i*8 + j
-						for {
-							acc := chk.accessed.Load()
-							if (acc & (1 << bit)) != 0 {
-								break // already accessed
-							}
-							if chk.accessed.CompareAndSwap(acc, acc|(1<<bit)) {
-								break
-							}
-						}
-
-						c.stats[stripeIdx].hits.Add(1)
-						return handler, copiedParams, true
+// get implements Get and Lookup for a key with extra parts (nil for none)
+// within a namespace (nil for the shared key space). status is only set for a
+// NegativeHit.
+func (c *LRUCache) get(method, path string, extra *[MaxKeyExtra]string, dst []Param, space *Namespace) (HandlerFunc, []Param, int, Result) {
+	ns := space.ident()
+	method, path = c.normalizeKey(method, path)
+	group, stripeIdx, sig, psig := c.locate(method, path, extra, ns)
+
+	if chk, idx, bit, seq1, ok := c.probe(group, sig, psig, method, path, extra, ns); ok {
+		// Safely read data
+		handler := c.handler(idx)
+		copiedParams, ok := c.loadParams(idx, dst)
+		deadline := c.state(idx).deadline.Load()
+		status := c.state(idx).status.Load()
+
+		// Validate read seqlock
+		if ok && c.state(idx).seq.Load() == seq1 {
+			if status != 0 {
+				if c.now() < deadline {
+					markAccessed(chk, bit)
+					c.stats.at(stripeIdx).negHits.Add(1)
+					if space != nil {
+						space.stats.at(stripeIdx & 7).negHits.Add(1)
 					}
+					return nil, nil, int(status), NegativeHit
+				}
+				// Expired negative entries are left for CLOCK to evict.
+				c.stats.at(stripeIdx).negExpired.Add(1)
+				if space != nil {
+					space.stats.at(stripeIdx & 7).negExpired.Add(1)
+				}
+			} else {
+				markAccessed(chk, bit)
+				if deadline != 0 {
+					c.loader.check(c, idx, seq1, deadline, method, path, extra)
 				}
+				c.stats.at(stripeIdx).hits.Add(1)
+				if space != nil {
+					space.stats.at(stripeIdx & 7).hits.Add(1)
+				}
+				return handler, copiedParams, 0, Hit
+			}
+		}
+	}
+
+	if c.victims != nil {
+		if v := c.victims.take(uint8(sig), method, path, extra, ns, c.gen.Load()); v != nil {
+			c.promote(v)
+			c.victims.hits.Add(1)
+			c.stats.at(stripeIdx).hits.Add(1)
+			if space != nil {
+				space.stats.at(stripeIdx & 7).hits.Add(1)
 			}
+			var params []Param
+			if len(v.params) > 0 {
+				params = append(dst[:0], v.params...)
+			}
+			return v.handler, params, 0, Hit
 		}
 	}
 
-	c.stats[stripeIdx].misses.Add(1)
-	return nil, nil, false
+	c.stats.at(stripeIdx).misses.Add(1)
+	if space != nil {
+		space.stats.at(stripeIdx & 7).misses.Add(1)
+	}
+	return nil, nil, 0, Miss
+}
+
+// drop counts a shed insert against the cache and, if set, its namespace.
+func (c *LRUCache) drop(stripeIdx uint64, space *Namespace) {
+	c.stats.at(stripeIdx).drops.Add(1)
+	if space != nil {
+		space.stats.at(stripeIdx & 7).drops.Add(1)
+	}
 }
 
-// Clear gracefully removes all entries from the cache lock-free.
+// Clear gracefully removes all entries from the cache lock-free and releases
+// the memory they reference. Slots that are mid-write are left to their
+// writers. Unlike InvalidateAll it visits every slot.
 func (c *LRUCache) Clear() {
 	for group := uint32(0); group < c.numGroups; group++ {
 		chk := &c.chunks[group]
 
+		var claimed uint64
 		for {
 			w := chk.writing.Load()
 			// Claim all non-writing slots
-			toClaim := ^w
-			if chk.writing.CompareAndSwap(w, w|toClaim) {
+			claimed = ^w
+			if chk.writing.CompareAndSwap(w, w|claimed) {
 				break
 			}
 		}
 
-		// Clear valid and accessed
-		chk.valid.Store(0)
-		chk.accessed.Store(0)
-
-		// Clear signatures
-		for i := 0; i < 8; i++ {
-			chk.sigs[i].Store(0)
+		for m := claimed; m != 0; m &= m - 1 {
+			bit := uint32(bits.TrailingZeros64(m))
+			c.clearSlot(chk, group*64+bit, bit)
 		}
 
-		for bit := uint32(0); bit < 64; bit++ {
-			idx := group*64 + bit
-			c.params[idx].Store(nil)
-			c.methods[idx].Store("")
-			c.paths[idx].Store("")
-			c.handlers[idx].Store(nil)
-			c.states[idx].seq.Store(0)
-		}
+		chk.writing.And(^claimed)
+	}
+	if c.victims != nil {
+		c.victims.clear()
+	}
 
-		chk.writing.Store(0)
+	for i := uint64(0); i < 64; i++ {
+		c.stats.at(i).hits.Store(0)
+		c.stats.at(i).misses.Store(0)
 	}
+}
+
+// clearSlot empties a slot whose writing bit the caller owns and releases the
+// memory it references. The seqlock is bumped rather than reset so in-flight
+// readers always notice the removal.
+func (c *LRUCache) clearSlot(chk *chunk, idx, bit uint32) {
+	seq := c.state(idx).seq.Load()
+	c.state(idx).seq.Store(seq + 1) // odd
 
-	for i := 0; i < 64; i++ {
-		c.stats[i].hits.Store(0)
-		c.stats[i].misses.Store(0)
+	if chk.valid.Load()&(1<<bit) != 0 {
+		c.disown(idx)
 	}
+	chk.valid.And(^(uint64(1) << bit))
+	chk.accessed.And(^(uint64(1) << bit))
+	if c.arena != nil {
+		c.release(idx, c.state(idx).ref.Swap(0))
+	} else if c.records != nil {
+		c.records[idx].Store(nil)
+	} else {
+		c.params[idx].Store(nil)
+		c.methods[idx].Store("")
+		c.paths[idx].Store("")
+		for i := range c.extras {
+			c.extras[i][idx].Store("")
+		}
+	}
+	c.setHandler(idx, nil)
+	if c.values != nil {
+		c.values[idx].Store(nil)
+	}
+
+	c.state(idx).seq.Store(seq + 2)
+}
+
+// InvalidateAll logically empties the cache in O(1) with a single atomic
+// increment of the cache generation. Entries written before the call read as
+// misses immediately; their slots are reclaimed lazily as preferred victims
+// the next time a writer touches each set. The memory they reference is
+// released as the slots are reused, not up front as with Clear.
+func (c *LRUCache) InvalidateAll() {
+	c.gen.Add(1)
 }
 
 // Stats returns cache hit/miss/drop statistics.
 func (c *LRUCache) Stats() (hits, misses, drops int64, ratio float64) {
-	for i := 0; i < 64; i++ {
-		hits += c.stats[i].hits.Load()
-		misses += c.stats[i].misses.Load()
-		drops += c.stats[i].drops.Load()
+	for i := uint64(0); i < 64; i++ {
+		hits += c.stats.at(i).hits.Load()
+		misses += c.stats.at(i).misses.Load()
+		drops += c.stats.at(i).drops.Load()
 	}
 	total := hits + misses
 	if total > 0 {
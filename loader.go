@@ -0,0 +1,143 @@
+package liteLRU
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Loader produces the handler and params for a route. It is called by Load to
+// fill misses and in the background to refresh entries past their deadline.
+type Loader func(method, path string) (HandlerFunc, []Param, error)
+
+// ErrNoLoader is returned by Load on a cache created without WithLoader.
+var ErrNoLoader = errors.New("liteLRU: cache has no loader")
+
+// loader holds the WithLoader settings and refresh counters. Deadlines are
+// read from the cache's monotonic clock and always even, so that the low bit
+// of a slot's deadline can mark a refresh in flight.
+type loader struct {
+	load      Loader
+	freshFor  int64
+	ahead     int64
+	refreshes atomic.Int64
+	failures  atomic.Int64
+
+	mu       sync.Mutex // orders starting refreshes against close
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+// newLoader returns the refresher configured by WithLoader, or nil.
+func newLoader(cfg *config) *loader {
+	if cfg.load == nil {
+		return nil
+	}
+	return &loader{
+		load:     cfg.load,
+		freshFor: int64(cfg.freshFor),
+		ahead:    int64(cfg.refreshAhead),
+	}
+}
+
+// deadline returns the soft deadline of an entry written at now, or 0 (no
+// deadline) if the cache has no loader.
+func (l *loader) deadline(now int64) int64 {
+	if l == nil {
+		return 0
+	}
+	return (now + l.freshFor + 2) &^ 1
+}
+
+// check is called on a hit of slot idx that read deadline under seqlock seq.
+// If the entry is stale or within the refresh-ahead window it claims the
+// refresh by setting the deadline's low bit and reloads the entry in the
+// background. The claim fails if the slot was rewritten since or another
+// refresh is already running.
+func (l *loader) check(c *LRUCache, idx, seq uint32, deadline int64, method, path string, extra *[MaxKeyExtra]string) {
+	if deadline&1 != 0 || c.now() < deadline-l.ahead {
+		return
+	}
+	st := c.state(idx)
+	var meta entryMeta
+	if extra != nil {
+		parts := *extra
+		meta.extra = &parts
+	}
+	meta.ns = st.ns.Load()
+	for i := range meta.tags {
+		meta.tags[i] = st.tags[i].Load()
+	}
+	if c.values != nil {
+		meta.value = c.values[idx].Load()
+	}
+	// The metadata must belong to the entry the deadline was read from.
+	if st.seq.Load() != seq || !st.deadline.CompareAndSwap(deadline, deadline|1) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		st.deadline.CompareAndSwap(deadline|1, deadline)
+		return
+	}
+	l.inflight.Add(1)
+	go func() {
+		defer l.inflight.Done()
+		l.refresh(c, idx, deadline, method, path, &meta)
+	}()
+}
+
+// close stops new refreshes from starting and waits for those in flight,
+// including their calls to the loader, so that none writes to the cache after
+// it is unmapped.
+func (l *loader) close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.inflight.Wait()
+}
+
+// refresh reloads an entry whose refresh check claimed. The rewrite stamps a
+// new deadline, releasing the claim; if the load fails or the rewrite is shed,
+// the claim is released explicitly so a later hit retries.
+func (l *loader) refresh(c *LRUCache, idx uint32, deadline int64, method, path string, meta *entryMeta) {
+	handler, params, err := l.load(method, path)
+	if err != nil {
+		l.failures.Add(1)
+	} else {
+		l.refreshes.Add(1)
+		c.add(method, path, handler, params, meta)
+	}
+	c.state(idx).deadline.CompareAndSwap(deadline|1, deadline)
+}
+
+// Load returns the entry for method and path like Get, calling the loader
+// configured with WithLoader on a miss and caching its result. Concurrent
+// misses for the same route may each call the loader. On a miss the returned
+// params are the slice the loader produced, not dst.
+func (c *LRUCache) Load(method, path string, dst []Param) (HandlerFunc, []Param, error) {
+	if handler, params, ok := c.Get(method, path, dst); ok {
+		return handler, params, nil
+	}
+	if c.loader == nil {
+		return nil, nil, ErrNoLoader
+	}
+	handler, params, err := c.loader.load(method, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Add(method, path, handler, params)
+	return handler, params, nil
+}
+
+// RefreshStats returns how many background refreshes succeeded and failed.
+func (c *LRUCache) RefreshStats() (refreshes, failures int64) {
+	if c.loader == nil {
+		return 0, 0
+	}
+	return c.loader.refreshes.Load(), c.loader.failures.Load()
+}
@@ -0,0 +1,249 @@
+package liteLRU
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLoaderCache returns a cache whose loader reports the number of loads so
+// far as the "v" param, driven by a manual clock. If gate is non-nil each
+// load waits for it to be closed.
+func newLoaderCache(t *testing.T, freshFor, ahead time.Duration, gate chan struct{}) (*LRUCache, *atomic.Int64, *atomic.Int64, chan struct{}) {
+	t.Helper()
+	var calls, clock atomic.Int64
+	loaded := make(chan struct{}, 16)
+	load := func(method, path string) (HandlerFunc, []Param, error) {
+		if gate != nil {
+			<-gate
+		}
+		n := calls.Add(1)
+		defer func() { loaded <- struct{}{} }()
+		return nil, []Param{{Key: "v", Value: strconv.FormatInt(n, 10)}}, nil
+	}
+	c := NewLRUCache(256, 10, WithLoader(load, freshFor, ahead))
+	t.Cleanup(c.Close)
+	c.now = clock.Load
+	return c, &calls, &clock, loaded
+}
+
+func waitLoaded(t *testing.T, loaded chan struct{}) {
+	t.Helper()
+	select {
+	case <-loaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("background refresh did not run")
+	}
+}
+
+// slotOf returns the slot holding a route and its current seqlock value.
+func slotOf(t *testing.T, c *LRUCache, path string, ns uint32) (idx, seq uint32) {
+	t.Helper()
+	group, _, sig, _ := c.locate("GET", path, nil, ns)
+	_, idx, _, seq, ok := c.lookup(group, sig, "GET", path, nil, ns)
+	if !ok {
+		t.Fatalf("%s not cached", path)
+	}
+	return idx, seq
+}
+
+// waitRewritten polls the seqlock of slot idx until a write that started
+// after seq has completed, so the test never reads concurrently with it.
+func waitRewritten(t *testing.T, c *LRUCache, idx, seq uint32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if s := c.state(idx).seq.Load(); s != seq && s%2 == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("entry was never rewritten")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoadFillsMisses(t *testing.T) {
+	c, calls, _, loaded := newLoaderCache(t, time.Minute, 0, nil)
+
+	_, p, err := c.Load("GET", "/a", nil)
+	if err != nil || p[0].Value != "1" {
+		t.Fatalf("Load = %+v, %v", p, err)
+	}
+	<-loaded
+	if _, p, err = c.Load("GET", "/a", nil); err != nil || p[0].Value != "1" || calls.Load() != 1 {
+		t.Fatalf("second Load = %+v, %v after %d loads", p, err, calls.Load())
+	}
+
+	plain := NewLRUCache(64, 10)
+	defer plain.Close()
+	if _, _, err := plain.Load("GET", "/a", nil); !errors.Is(err, ErrNoLoader) {
+		t.Fatalf("Load without loader: %v", err)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	gate := make(chan struct{})
+	c, calls, clock, loaded := newLoaderCache(t, time.Second, 0, gate)
+	c.Add("GET", "/a", nil, []Param{{Key: "v", Value: "0"}})
+
+	clock.Store(int64(500 * time.Millisecond))
+	if _, p, _ := c.Get("GET", "/a", nil); p[0].Value != "0" || calls.Load() != 0 {
+		t.Fatal("fresh entry should not be refreshed")
+	}
+
+	clock.Store(int64(2 * time.Second))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, p, ok := c.Get("GET", "/a", nil); !ok || (p[0].Value != "0" && p[0].Value != "1") {
+				t.Errorf("stale hit returned %v %+v", ok, p)
+			}
+		}()
+	}
+	wg.Wait()
+	idx, seq := slotOf(t, c, "/a", 0)
+	close(gate) // let the refresh rewrite the entry only after the stale hits
+	waitLoaded(t, loaded)
+	waitRewritten(t, c, idx, seq)
+	if calls.Load() != 1 {
+		t.Fatalf("stale entry refreshed %d times", calls.Load())
+	}
+	if refreshes, _ := c.RefreshStats(); refreshes != 1 {
+		t.Fatalf("refreshes = %d", refreshes)
+	}
+
+	// The refreshed entry carries a new deadline.
+	if _, p, _ := c.Get("GET", "/a", nil); p[0].Value != "1" || calls.Load() != 1 {
+		t.Fatal("refreshed entry should be fresh")
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	c, calls, clock, loaded := newLoaderCache(t, 10*time.Second, 2*time.Second, nil)
+	ns := c.Namespace("tenant", 0)
+	ns.Add("GET", "/hot", nil, []Param{{Key: "v", Value: "0"}})
+
+	clock.Store(int64(7 * time.Second))
+	ns.Get("GET", "/hot", nil)
+	if calls.Load() != 0 {
+		t.Fatal("refresh started outside the refresh-ahead window")
+	}
+
+	clock.Store(int64(9 * time.Second))
+	idx, seq := slotOf(t, c, "/hot", ns.id)
+	if _, p, _ := ns.Get("GET", "/hot", nil); p[0].Value != "0" {
+		t.Fatalf("refresh-ahead hit returned %+v", p)
+	}
+	waitLoaded(t, loaded)
+	waitRewritten(t, c, idx, seq)
+	if _, p, ok := ns.Get("GET", "/hot", nil); !ok || p[0].Value != "1" {
+		t.Fatalf("refresh-ahead result not stored in the namespace: %v %+v", ok, p)
+	}
+	if ns.Len() != 1 {
+		t.Fatalf("namespace holds %d entries", ns.Len())
+	}
+}
+
+func TestRefreshFailureKeepsStaleEntry(t *testing.T) {
+	var calls atomic.Int64
+	var clock atomic.Int64
+	done := make(chan struct{}, 4)
+	load := func(method, path string) (HandlerFunc, []Param, error) {
+		calls.Add(1)
+		defer func() { done <- struct{}{} }()
+		return nil, nil, errors.New("upstream down")
+	}
+	c := NewLRUCache(64, 10, WithLoader(load, time.Second, 0))
+	defer c.Close()
+	c.now = clock.Load
+
+	c.Add("GET", "/a", nil, []Param{{Key: "v", Value: "old"}})
+	idx, _ := slotOf(t, c, "/a", 0)
+	clock.Store(int64(5 * time.Second))
+	c.Get("GET", "/a", nil)
+	waitLoaded(t, done)
+
+	// The claim is released once the failure is recorded.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, failures := c.RefreshStats(); failures == 1 && c.state(idx).deadline.Load()&1 == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("failed refresh never released its claim")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, p, ok := c.Get("GET", "/a", nil); !ok || p[0].Value != "old" {
+		t.Fatalf("stale entry lost after failed refresh: %v %+v", ok, p)
+	}
+	waitLoaded(t, done)
+	if calls.Load() != 2 {
+		t.Fatalf("failed refresh not retried: %d loads", calls.Load())
+	}
+}
+
+func TestRefreshKeepsValue(t *testing.T) {
+	var clock atomic.Int64
+	loaded := make(chan struct{}, 1)
+	load := func(method, path string) (HandlerFunc, []Param, error) {
+		defer func() { loaded <- struct{}{} }()
+		return nil, []Param{{Key: "v", Value: "1"}}, nil
+	}
+	c := NewLRUCache(64, 10, WithValues(), WithLoader(load, time.Second, 0))
+	defer c.Close()
+	c.now = clock.Load
+
+	c.AddValue("GET", "/a", "payload")
+	clock.Store(int64(2 * time.Second))
+	idx, seq := slotOf(t, c, "/a", 0)
+	if _, _, ok := c.Get("GET", "/a", nil); !ok {
+		t.Fatal("expected stale hit")
+	}
+	waitLoaded(t, loaded)
+	waitRewritten(t, c, idx, seq)
+
+	if v, ok := c.PeekValue("GET", "/a"); !ok || v != "payload" {
+		t.Fatalf("refresh replaced the value with %v (found %v)", v, ok)
+	}
+	if _, p, _ := c.Peek("GET", "/a", nil); len(p) != 1 || p[0].Value != "1" {
+		t.Fatalf("refresh not applied: %+v", p)
+	}
+}
+
+func TestCloseWaitsForRefresh(t *testing.T) {
+	var clock atomic.Int64
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	load := func(method, path string) (HandlerFunc, []Param, error) {
+		close(started)
+		<-gate
+		return nil, []Param{{Key: "v", Value: "1"}}, nil
+	}
+	c := NewLRUCache(64, 10, WithLoader(load, time.Second, 0))
+	c.now = clock.Load
+	c.Add("GET", "/a", nil, nil)
+	clock.Store(int64(2 * time.Second))
+	c.Get("GET", "/a", nil)
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a refresh was blocked in the loader")
+	case <-time.After(20 * time.Millisecond):
+	}
+	// The refresh writes its result before Close unmaps the slabs.
+	close(gate)
+	<-closed
+}
@@ -2,5 +2,6 @@
 
 package liteLRU
 
-// CacheLineSize is set to 64 bytes for x86_64 architectures.
+// CacheLineSize is set to 64 bytes for x86_64 architectures. It is the
+// fallback when the line size cannot be detected at run time.
 const CacheLineSize = 64
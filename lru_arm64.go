@@ -3,4 +3,6 @@
 package liteLRU
 
 // CacheLineSize is set to 128 bytes for ARM64 architectures (e.g., Apple Silicon M-series L2/L3).
+// It is the fallback when the line size cannot be detected at run time, so
+// 64-byte-line servers running Linux are not padded twice over.
 const CacheLineSize = 128
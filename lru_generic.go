@@ -3,4 +3,5 @@
 package liteLRU
 
 // CacheLineSize is set to 64 bytes as the industry standard for modern architectures.
+// It is the fallback when the line size cannot be detected at run time.
 const CacheLineSize = 64
@@ -0,0 +1,28 @@
+//go:build amd64 && !purego
+
+package liteLRU
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/sys/cpu"
+)
+
+// useAVX2 selects the two-load AVX2 scan over the four-load SSE2 one.
+var useAVX2 = cpu.X86.HasAVX2
+
+// matchSigs compares all 64 signature bytes of a plane against sig in one
+// pass and returns a mask with bit i set for every slot i whose byte equals
+// sig. SSE2 is part of the amd64 baseline, so it needs no fallback.
+func matchSigs(plane *[8]atomic.Uint64, sig uint8) uint64 {
+	if useAVX2 {
+		return matchSigsAVX2(plane, sig)
+	}
+	return matchSigsSSE2(plane, sig)
+}
+
+//go:noescape
+func matchSigsSSE2(plane *[8]atomic.Uint64, sig uint8) uint64
+
+//go:noescape
+func matchSigsAVX2(plane *[8]atomic.Uint64, sig uint8) uint64
@@ -0,0 +1,54 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func matchSigsSSE2(plane *[8]atomic.Uint64, sig uint8) uint64
+TEXT ·matchSigsSSE2(SB), NOSPLIT, $0-24
+	MOVQ    plane+0(FP), SI
+	MOVBLZX sig+8(FP), AX
+
+	// Broadcast sig to all 16 bytes of X0.
+	MOVQ      AX, X0
+	PUNPCKLBW X0, X0
+	PUNPCKLWL X0, X0
+	PSHUFL    $0, X0, X0
+
+	MOVOU 0(SI), X1
+	MOVOU 16(SI), X2
+	MOVOU 32(SI), X3
+	MOVOU 48(SI), X4
+	PCMPEQB X0, X1
+	PCMPEQB X0, X2
+	PCMPEQB X0, X3
+	PCMPEQB X0, X4
+
+	// One bit per byte, 16 slots per register.
+	PMOVMSKB X1, AX
+	PMOVMSKB X2, BX
+	PMOVMSKB X3, CX
+	PMOVMSKB X4, DX
+	SHLQ $16, BX
+	SHLQ $32, CX
+	SHLQ $48, DX
+	ORQ  BX, AX
+	ORQ  CX, AX
+	ORQ  DX, AX
+	MOVQ AX, ret+16(FP)
+	RET
+
+// func matchSigsAVX2(plane *[8]atomic.Uint64, sig uint8) uint64
+TEXT ·matchSigsAVX2(SB), NOSPLIT, $0-24
+	MOVQ         plane+0(FP), SI
+	VPBROADCASTB sig+8(FP), Y0
+
+	VPCMPEQB 0(SI), Y0, Y1
+	VPCMPEQB 32(SI), Y0, Y2
+
+	// One bit per byte, 32 slots per register.
+	VPMOVMSKB Y1, AX
+	VPMOVMSKB Y2, BX
+	VZEROUPPER
+	SHLQ $32, BX
+	ORQ  BX, AX
+	MOVQ AX, ret+16(FP)
+	RET
@@ -0,0 +1,32 @@
+//go:build amd64 && !purego
+
+package liteLRU
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+// TestMatchSigsAMD64 covers whichever of the SSE2 and AVX2 scans matchSigs
+// does not dispatch to on this CPU.
+func TestMatchSigsAMD64(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	var plane [8]atomic.Uint64
+	for round := 0; round < 2000; round++ {
+		for i := range plane {
+			// Bytes 0..3 only, so matches are dense.
+			plane[i].Store(r.Uint64() & 0x0303030303030303)
+		}
+		sig := uint8(r.Intn(4))
+		want := matchSigsSWAR(&plane, sig)
+		if got := matchSigsSSE2(&plane, sig); got != want {
+			t.Fatalf("SSE2: %064b, want %064b", got, want)
+		}
+		if useAVX2 {
+			if got := matchSigsAVX2(&plane, sig); got != want {
+				t.Fatalf("AVX2: %064b, want %064b", got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build arm64 && !purego
+
+package liteLRU
+
+import "sync/atomic"
+
+// matchSigs compares all 64 signature bytes of a plane against sig in one
+// pass with NEON and returns a mask with bit i set for every slot i whose
+// byte equals sig.
+//
+//go:noescape
+func matchSigs(plane *[8]atomic.Uint64, sig uint8) uint64
@@ -0,0 +1,37 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// func matchSigs(plane *[8]atomic.Uint64, sig uint8) uint64
+TEXT ·matchSigs(SB), NOSPLIT, $0-24
+	MOVD  plane+0(FP), R0
+	MOVBU sig+8(FP), R1
+
+	VLD1 (R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+	VDUP R1, V4.B16
+
+	// Per-lane bit weights 1, 2, 4, ... 128, repeated.
+	MOVD $0x8040201008040201, R2
+	VMOV R2, V5.D[0]
+	VMOV R2, V5.D[1]
+
+	VCMEQ V4.B16, V0.B16, V0.B16
+	VCMEQ V4.B16, V1.B16, V1.B16
+	VCMEQ V4.B16, V2.B16, V2.B16
+	VCMEQ V4.B16, V3.B16, V3.B16
+	VAND  V5.B16, V0.B16, V0.B16
+	VAND  V5.B16, V1.B16, V1.B16
+	VAND  V5.B16, V2.B16, V2.B16
+	VAND  V5.B16, V3.B16, V3.B16
+
+	// Three rounds of pairwise adds fold each run of 8 weighted lanes into
+	// one byte, so byte k of the low doubleword holds the mask of slots
+	// 8k..8k+7.
+	VADDP V1.B16, V0.B16, V0.B16
+	VADDP V3.B16, V2.B16, V2.B16
+	VADDP V2.B16, V0.B16, V0.B16
+	VADDP V0.B16, V0.B16, V0.B16
+
+	VMOV V0.D[0], R0
+	MOVD R0, ret+16(FP)
+	RET
@@ -0,0 +1,11 @@
+//go:build (!amd64 && !arm64) || purego
+
+package liteLRU
+
+import "sync/atomic"
+
+// matchSigs compares all 64 signature bytes of a plane against sig and
+// returns a mask with bit i set for every slot i whose byte equals sig.
+func matchSigs(plane *[8]atomic.Uint64, sig uint8) uint64 {
+	return matchSigsSWAR(plane, sig)
+}
@@ -0,0 +1,118 @@
+package liteLRU
+
+import (
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// slotKey returns the method and path of slot idx. In arena and compact mode
+// the strings alias the entry record. Callers validate the answer with the
+// slot seqlock.
+func (c *LRUCache) slotKey(idx uint32) (method, path string, ok bool) {
+	if c.methods == nil {
+		b := c.record(idx)
+		if b == nil {
+			return "", "", false
+		}
+		return recordKey(b)
+	}
+	return c.methods[idx].Load(), c.paths[idx].Load(), true
+}
+
+// scanPath calls fn for every valid, positive entry of path in the shared key
+// space, with its slot index, bit within the chunk, the even seqlock value
+// observed before the key was read and the entry's method. fn must validate
+// whatever it reads against seq, and returns false to stop the scan.
+func (c *LRUCache) scanPath(path string, fn func(idx, bit, seq uint32, method string) bool) {
+	group, _, psig := c.locatePath(path, nil, 0)
+	if !c.scanSet(group, psig, path, fn) || !c.twoChoice {
+		return
+	}
+	if alt := c.altGroup(group, psig); alt != group {
+		c.scanSet(alt, psig, path, fn)
+	}
+}
+
+// scanSet is scanPath for one candidate set. It returns false if fn stopped
+// the scan.
+func (c *LRUCache) scanSet(group uint32, psig uint8, path string, fn func(idx, bit, seq uint32, method string) bool) bool {
+	first, n, mask := c.span(group)
+	gen := c.gen.Load()
+	for k := first; k < first+n; k++ {
+		chk := &c.chunks[k]
+		for m := matchSigs(&chk.psigs, psig) & mask & chk.valid.Load(); m != 0; m &= m - 1 {
+			bit := uint32(bits.TrailingZeros64(m))
+			idx := k*64 + bit
+			st := c.state(idx)
+			seq := st.seq.Load()
+			if seq%2 != 0 || st.gen.Load() != gen || st.ns.Load() != 0 || st.status.Load() != 0 {
+				continue
+			}
+			method, p, ok := c.slotKey(idx)
+			if !ok || p != path || !c.keyEquals(idx, method, path, nil) {
+				continue
+			}
+			if !fn(idx, bit, seq, method) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Methods returns the methods with an entry cached for path, so a router can
+// answer 405 with an Allow header or build an OPTIONS response without
+// re-matching the route. Negative entries are not included. Every method of a
+// path lives in the same set (or pair of sets, see WithTwoChoice), and a
+// path-only signature plane maintained next to the route signatures lets
+// Methods scan just that set; it allocates only the returned slice, which is
+// nil if nothing is cached for path.
+func (c *LRUCache) Methods(path string) []string {
+	_, path = c.normalizeKey("", path)
+	var out []string
+	c.scanPath(path, func(idx, _, seq uint32, method string) bool {
+		if c.arena != nil {
+			method = strings.Clone(method)
+		}
+		if c.state(idx).seq.Load() == seq && !slices.Contains(out, method) {
+			out = append(out, method)
+		}
+		return true
+	})
+	return out
+}
+
+// GetAnyMethod retrieves the first entry cached for path under any method and
+// returns that method with the handler and params, as Get would. It serves
+// fallbacks such as answering HEAD or OPTIONS from whatever route exists.
+func (c *LRUCache) GetAnyMethod(path string, dst []Param) (string, HandlerFunc, []Param, bool) {
+	var (
+		method  string
+		handler HandlerFunc
+		params  []Param
+		found   bool
+	)
+	_, path = c.normalizeKey("", path)
+	_, pathHash, _ := c.locatePath(path, nil, 0)
+	c.scanPath(path, func(idx, bit, seq uint32, m string) bool {
+		h := c.handler(idx)
+		if c.arena != nil {
+			m = strings.Clone(m)
+		}
+		p, ok := c.loadParams(idx, dst)
+		if !ok || c.state(idx).seq.Load() != seq {
+			return true
+		}
+		markAccessed(&c.chunks[idx/64], bit)
+		method, handler, params, found = m, h, p, true
+		return false
+	})
+
+	if found {
+		c.stats.at(pathHash & 63).hits.Add(1)
+	} else {
+		c.stats.at(pathHash & 63).misses.Add(1)
+	}
+	return method, handler, params, found
+}
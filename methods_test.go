@@ -0,0 +1,60 @@
+package liteLRU
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithArena(0)}} {
+		c := NewLRUCache(1024, 10, opts...)
+
+		for _, m := range []string{"GET", "POST", "DELETE"} {
+			c.Add(m, "/x", nil, []Param{{Key: "m", Value: m}})
+		}
+		c.AddNotFound("PUT", "/x", 405)
+		c.Namespace("tenant", 0).Add("PATCH", "/x", nil, nil)
+		for i := 0; i < 500; i++ {
+			c.Add("GET", fmt.Sprintf("/other/%d", i), nil, nil)
+		}
+
+		got := c.Methods("/x")
+		slices.Sort(got)
+		if !slices.Equal(got, []string{"DELETE", "GET", "POST"}) {
+			t.Fatalf("Methods(/x) = %v", got)
+		}
+		if got := c.Methods("/missing"); got != nil {
+			t.Fatalf("Methods(/missing) = %v", got)
+		}
+
+		method, _, params, ok := c.GetAnyMethod("/x", nil)
+		if !ok || len(params) != 1 || params[0].Value != method {
+			t.Fatalf("GetAnyMethod = %q %+v %v", method, params, ok)
+		}
+		if _, _, _, ok := c.GetAnyMethod("/missing", nil); ok {
+			t.Fatal("GetAnyMethod found a missing path")
+		}
+
+		c.InvalidateAll()
+		if got := c.Methods("/x"); got != nil {
+			t.Fatalf("Methods after InvalidateAll = %v", got)
+		}
+		c.Close()
+	}
+}
+
+func TestMethodsShareSet(t *testing.T) {
+	c := NewLRUCache(4096, 10)
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("/r/%d", i)
+		want, _, _, _ := c.locate("GET", path, nil, 0)
+		for _, m := range []string{"POST", "DELETE", "OPTIONS"} {
+			if g, _, _, _ := c.locate(m, path, nil, 0); g != want {
+				t.Fatalf("%s %s placed in set %d, GET in %d", m, path, g, want)
+			}
+		}
+	}
+}
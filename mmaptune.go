@@ -0,0 +1,80 @@
+package liteLRU
+
+import "errors"
+
+// WithHugePages asks the kernel to back the off-heap slabs (seqlocks, chunks
+// and the arena) with transparent huge pages via madvise(MADV_HUGEPAGE), so
+// that lookups across a large cache stop thrashing the TLB. It is advisory:
+// MmapStatus reports whether the kernel accepted it.
+func WithHugePages() Option {
+	return func(cfg *config) {
+		cfg.hugePages = true
+	}
+}
+
+// WithPrefault faults in every page of the off-heap slabs during NewLRUCache,
+// so the first write to each page does not page-fault on the hot path. It
+// costs the full resident size of the slabs up front. MmapStatus reports
+// whether it succeeded. With OpenShared it needs Linux 5.14 or later.
+func WithPrefault() Option {
+	return func(cfg *config) {
+		cfg.prefault = true
+	}
+}
+
+// WithMlock locks the off-heap slabs into memory with mlock so they are never
+// swapped out. This usually needs CAP_IPC_LOCK or a large enough
+// RLIMIT_MEMLOCK; MmapStatus reports a refusal. The lock is released by Close.
+func WithMlock() Option {
+	return func(cfg *config) {
+		cfg.mlock = true
+	}
+}
+
+// MmapStatus reports how the kernel answered the mmap tuning options. Each
+// field is nil if its option was not requested or was applied to every
+// off-heap slab, and otherwise holds the first error. The options are
+// advisory: the cache behaves the same whatever the outcome. Outside Linux,
+// and for slabs that fell back to the Go heap, requested options report
+// errors.ErrUnsupported.
+type MmapStatus struct {
+	HugePages error // WithHugePages
+	Prefault  error // WithPrefault
+	Mlock     error // WithMlock
+}
+
+// MmapStatus returns the outcome of the mmap tuning options.
+func (c *LRUCache) MmapStatus() MmapStatus {
+	return c.mmap
+}
+
+// tuneSlabs applies the requested mmap tuning options to slabs, in the order
+// huge pages, prefault, mlock, so that pages are faulted in at their final
+// size. Nil slabs are heap fallbacks that cannot be tuned. The mapping of a
+// shared cache holds live data of other processes, so it is never prefaulted
+// by writing to it: on kernels without MADV_POPULATE_WRITE, Prefault reports
+// the error instead.
+func tuneSlabs(cfg *config, slabs ...[]byte) (st MmapStatus) {
+	prefault := prefaultSlab
+	if cfg.shared != nil {
+		prefault = populateSlab
+	}
+	apply := func(on bool, res *error, fn func([]byte) error) {
+		if !on {
+			return
+		}
+		for _, slab := range slabs {
+			err := errors.ErrUnsupported
+			if slab != nil {
+				err = fn(slab)
+			}
+			if err != nil && *res == nil {
+				*res = err
+			}
+		}
+	}
+	apply(cfg.hugePages, &st.HugePages, adviseHugePages)
+	apply(cfg.prefault, &st.Prefault, prefault)
+	apply(cfg.mlock, &st.Mlock, lockSlab)
+	return st
+}
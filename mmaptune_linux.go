@@ -0,0 +1,41 @@
+//go:build linux
+
+package liteLRU
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// adviseHugePages marks slab as eligible for transparent huge pages.
+func adviseHugePages(slab []byte) error {
+	return os.NewSyscallError("madvise", unix.Madvise(slab, unix.MADV_HUGEPAGE))
+}
+
+// populateSlab populates every page of slab writable without writing to it.
+// Kernels before 5.14 do not know MADV_POPULATE_WRITE and fail with EINVAL.
+func populateSlab(slab []byte) error {
+	return os.NewSyscallError("madvise", unix.Madvise(slab, unix.MADV_POPULATE_WRITE))
+}
+
+// prefaultSlab is populateSlab for a private slab. Where the kernel lacks
+// MADV_POPULATE_WRITE the pages are touched one by one instead, which is only
+// safe because such a slab is still zero and unpublished.
+func prefaultSlab(slab []byte) error {
+	err := unix.Madvise(slab, unix.MADV_POPULATE_WRITE)
+	if errors.Is(err, unix.EINVAL) {
+		page := os.Getpagesize()
+		for i := 0; i < len(slab); i += page {
+			slab[i] = 0
+		}
+		return nil
+	}
+	return os.NewSyscallError("madvise", err)
+}
+
+// lockSlab locks slab into memory. Munmap releases the lock.
+func lockSlab(slab []byte) error {
+	return os.NewSyscallError("mlock", unix.Mlock(slab))
+}
@@ -0,0 +1,51 @@
+package liteLRU
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// resident returns how many pages of slab are in memory.
+func resident(t *testing.T, slab []byte) (in, total int) {
+	page := os.Getpagesize()
+	vec := make([]byte, (len(slab)+page-1)/page)
+	_, _, errno := unix.Syscall(unix.SYS_MINCORE, uintptr(unsafe.Pointer(&slab[0])), uintptr(len(slab)), uintptr(unsafe.Pointer(&vec[0])))
+	if errno != 0 {
+		t.Skipf("mincore: %v", errno)
+	}
+	for _, v := range vec {
+		in += int(v & 1)
+	}
+	return in, len(vec)
+}
+
+func TestPrefaultMakesSlabsResident(t *testing.T) {
+	cold := NewLRUCache(1<<16, 10)
+	defer cold.Close()
+	if in, total := resident(t, cold.statesSlab); in == total {
+		t.Skip("slabs are resident without prefaulting")
+	}
+
+	c := NewLRUCache(1<<16, 10, WithPrefault())
+	defer c.Close()
+	for _, slab := range [][]byte{c.statesSlab, c.chunksSlab} {
+		if in, total := resident(t, slab); in != total {
+			t.Fatalf("%d of %d pages resident after prefault", in, total)
+		}
+	}
+}
+
+func TestMlockReportsRefusal(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithMlock())
+	defer c.Close()
+	// Depending on privileges and RLIMIT_MEMLOCK the kernel may refuse, but
+	// then it says why.
+	if err := c.MmapStatus().Mlock; err != nil &&
+		!errors.Is(err, unix.EPERM) && !errors.Is(err, unix.ENOMEM) && !errors.Is(err, unix.EAGAIN) {
+		t.Fatalf("unexpected mlock error %v", err)
+	}
+}
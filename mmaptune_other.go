@@ -0,0 +1,15 @@
+//go:build !linux
+
+package liteLRU
+
+import "errors"
+
+// The mmap tuning options are only implemented on Linux.
+
+func adviseHugePages([]byte) error { return errors.ErrUnsupported }
+
+func populateSlab([]byte) error { return errors.ErrUnsupported }
+
+func prefaultSlab([]byte) error { return errors.ErrUnsupported }
+
+func lockSlab([]byte) error { return errors.ErrUnsupported }
@@ -0,0 +1,45 @@
+package liteLRU
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestMmapTuningDefault(t *testing.T) {
+	c := NewLRUCache(1024, 10)
+	defer c.Close()
+	if st := c.MmapStatus(); st != (MmapStatus{}) {
+		t.Fatalf("untuned cache reports %+v", st)
+	}
+}
+
+func TestMmapTuningHeapFallback(t *testing.T) {
+	// A slab that fell back to the heap cannot be tuned; options that were
+	// not requested stay quiet.
+	st := tuneSlabs(&config{prefault: true, mlock: true}, nil)
+	if !errors.Is(st.Prefault, errors.ErrUnsupported) || !errors.Is(st.Mlock, errors.ErrUnsupported) || st.HugePages != nil {
+		t.Fatalf("heap slab: %+v", st)
+	}
+}
+
+func TestMmapTuningOptions(t *testing.T) {
+	c := NewLRUCache(1<<14, 10, WithHugePages(), WithPrefault(), WithMlock(), WithArena(0))
+	defer c.Close()
+
+	st := c.MmapStatus()
+	t.Logf("huge pages: %v, prefault: %v, mlock: %v", st.HugePages, st.Prefault, st.Mlock)
+	if runtime.GOOS != "linux" {
+		if !errors.Is(st.HugePages, errors.ErrUnsupported) || !errors.Is(st.Prefault, errors.ErrUnsupported) || !errors.Is(st.Mlock, errors.ErrUnsupported) {
+			t.Fatalf("%s: %+v", runtime.GOOS, st)
+		}
+	} else if st.Prefault != nil {
+		t.Fatalf("prefault failed: %v", st.Prefault)
+	}
+
+	// Whatever the kernel said, the cache works.
+	c.Add("GET", "/tuned", nil, []Param{{Key: "k", Value: "v"}})
+	if _, p, ok := c.Get("GET", "/tuned", nil); !ok || p[0].Value != "v" {
+		t.Fatalf("Get = %v %+v", ok, p)
+	}
+}
@@ -0,0 +1,187 @@
+// Package muxcache puts a liteLRU cache in front of http.ServeMux route
+// resolution.
+//
+// A Mux registers routes on an ordinary ServeMux, so patterns, precedence,
+// redirects and 404/405 handling are exactly the ServeMux ones. Each time the
+// ServeMux dispatches a request to a registered handler, the resolution — the
+// route, its pattern and the request's path values — is cached under the
+// request's method, host and path, scoped to the Mux. Later requests for the same key skip
+// pattern matching: the Mux restores r.Pattern and every r.PathValue and calls
+// the route's handler directly. Requests the ServeMux answers itself
+// (redirects, 404, 405) are never cached.
+package muxcache
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+// Pseudo param keys of a cached resolution; path values follow them.
+const (
+	keyRoute = ":route"
+	keyGen   = ":gen"
+)
+
+// nextID gives every Mux a distinct invalidation tag.
+var nextID atomic.Uint64
+
+// route is a registered pattern and its handler.
+type route struct {
+	pattern string
+	handler http.Handler
+	names   []string // wildcard names of the pattern
+}
+
+// missKey marks requests dispatched through the ServeMux by a cache miss.
+type missKey struct{}
+
+// miss carries the registration generation read before a miss was resolved.
+type miss struct{ gen uint64 }
+
+// Mux is an http.Handler that serves like an http.ServeMux with cached route
+// resolution. Routes may be registered while serving; every registration
+// invalidates the resolutions cached so far.
+type Mux struct {
+	mux    *http.ServeMux
+	cache  *liteLRU.LRUCache
+	tag    string
+	prefix string // starts every cache key of this Mux
+
+	mu     sync.Mutex // serialises registration
+	routes atomic.Pointer[[]*route]
+	gen    atomic.Uint64 // odd while a registration is in progress
+}
+
+// New creates an empty Mux that caches resolutions in cache. The cache may be
+// shared with other users, including other Muxes; the Mux only reads and
+// invalidates its own entries.
+func New(cache *liteLRU.LRUCache) *Mux {
+	tag := "muxcache:" + strconv.FormatUint(nextID.Add(1), 10)
+	m := &Mux{
+		mux:    http.NewServeMux(),
+		cache:  cache,
+		tag:    tag,
+		prefix: tag + " ",
+	}
+	m.routes.Store(&[]*route{})
+	return m
+}
+
+// Handle registers handler for pattern, with ServeMux pattern syntax. Like
+// http.ServeMux.Handle it panics on an invalid or conflicting pattern.
+func (m *Mux) Handle(pattern string, handler http.Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := *m.routes.Load()
+	rt := &route{pattern: pattern, handler: handler, names: wildcards(pattern)}
+	idx := strconv.Itoa(len(old))
+
+	m.gen.Add(1)
+	defer func() {
+		m.gen.Add(1)
+		m.cache.InvalidateTag(m.tag)
+	}()
+	m.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ms, ok := r.Context().Value(missKey{}).(*miss); ok {
+			m.store(r, rt, idx, ms.gen)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	next := append(old[:len(old):len(old)], rt)
+	m.routes.Store(&next)
+}
+
+// HandleFunc registers the handler function for pattern.
+func (m *Mux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Handler returns the handler and pattern the underlying ServeMux resolves r
+// to, without using the cache.
+func (m *Mux) Handler(r *http.Request) (h http.Handler, pattern string) {
+	return m.mux.Handler(r)
+}
+
+// ServeHTTP dispatches r from the cache if its resolution is cached and still
+// current, and through the ServeMux otherwise.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gen := m.gen.Load()
+	key := m.cacheKey(r)
+
+	var buf [8]liteLRU.Param
+	if _, params, ok := m.cache.Get(r.Method, key, buf[:0]); ok && len(params) >= 2 && current(params[1].Value, gen) {
+		routes := *m.routes.Load()
+		if i, err := strconv.Atoi(params[0].Value); err == nil && i < len(routes) {
+			rt := routes[i]
+			r.Pattern = rt.pattern
+			for _, p := range params[2:] {
+				r.SetPathValue(p.Key, p.Value)
+			}
+			rt.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if gen%2 != 0 {
+		m.mux.ServeHTTP(w, r)
+		return
+	}
+	m.mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), missKey{}, &miss{gen: gen})))
+}
+
+// current reports whether a cached resolution made under generation stored
+// is valid at generation gen.
+func current(stored string, gen uint64) bool {
+	g, err := strconv.ParseUint(stored, 10, 64)
+	return err == nil && g == gen && gen%2 == 0
+}
+
+// store caches the resolution of r to rt, made under registration generation
+// gen, unless routes were registered since.
+func (m *Mux) store(r *http.Request, rt *route, idx string, gen uint64) {
+	if m.gen.Load() != gen {
+		return
+	}
+	params := make([]liteLRU.Param, 2, 2+len(rt.names))
+	params[0] = liteLRU.Param{Key: keyRoute, Value: idx}
+	params[1] = liteLRU.Param{Key: keyGen, Value: strconv.FormatUint(gen, 10)}
+	for _, name := range rt.names {
+		params = append(params, liteLRU.Param{Key: name, Value: r.PathValue(name)})
+	}
+	m.cache.AddTagged(r.Method, m.cacheKey(r), nil, params, m.tag)
+}
+
+// cacheKey is the host and escaped path a resolution is cached under, behind
+// the Mux's prefix: route indexes and generations mean nothing to another Mux
+// on the same cache. The method is the cache's own method key.
+func (m *Mux) cacheKey(r *http.Request) string {
+	return m.prefix + r.Host + r.URL.EscapedPath()
+}
+
+// wildcards returns the names of the wildcards in a ServeMux pattern.
+func wildcards(pattern string) []string {
+	var names []string
+	for {
+		i := strings.IndexByte(pattern, '{')
+		if i < 0 {
+			return names
+		}
+		pattern = pattern[i+1:]
+		j := strings.IndexByte(pattern, '}')
+		if j < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(pattern[:j], "...")
+		if name != "$" && name != "" {
+			names = append(names, name)
+		}
+		pattern = pattern[j+1:]
+	}
+}
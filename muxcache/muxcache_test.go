@@ -0,0 +1,136 @@
+package muxcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xDarkicex/liteLRU"
+)
+
+func newTestMux(t *testing.T) *Mux {
+	t.Helper()
+	cache := liteLRU.NewLRUCache(256, 16)
+	t.Cleanup(cache.Close)
+	return New(cache)
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestMuxRestoresPatternAndPathValues(t *testing.T) {
+	m := newTestMux(t)
+	var calls atomic.Int64
+	m.HandleFunc("GET /users/{id}/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(r.Pattern + "|" + r.PathValue("id") + "|" + r.PathValue("path")))
+	})
+
+	want := "GET /users/{id}/files/{path...}|42|a/b c"
+	for i := 0; i < 3; i++ {
+		if got := serve(m, "GET", "/users/42/files/a/b%20c").Body.String(); got != want {
+			t.Fatalf("request %d: got %q", i, got)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("handler ran %d times", calls.Load())
+	}
+	if hits, _, _, _ := m.cache.Stats(); hits != 2 {
+		t.Fatalf("cache hits = %d, want 2", hits)
+	}
+
+	// Other path values resolve separately.
+	if got := serve(m, "GET", "/users/7/files/x").Body.String(); got != "GET /users/{id}/files/{path...}|7|x" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestMuxLeavesServeMuxResponsesUncached(t *testing.T) {
+	m := newTestMux(t)
+	m.HandleFunc("GET /only-get", func(w http.ResponseWriter, r *http.Request) {})
+	m.HandleFunc("/dir/", func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 2; i++ {
+		if w := serve(m, "POST", "/only-get"); w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") == "" {
+			t.Fatalf("405 lost: %d %v", w.Code, w.Header())
+		}
+		if w := serve(m, "GET", "/missing"); w.Code != http.StatusNotFound {
+			t.Fatalf("404 lost: %d", w.Code)
+		}
+		if w := serve(m, "GET", "/dir"); w.Header().Get("Location") != "/dir/" {
+			t.Fatalf("redirect lost: %d %v", w.Code, w.Header())
+		}
+	}
+	if hits, _, _, _ := m.cache.Stats(); hits != 0 {
+		t.Fatalf("ServeMux responses were cached: %d hits", hits)
+	}
+}
+
+func TestMuxRegistrationInvalidates(t *testing.T) {
+	m := newTestMux(t)
+	m.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("prefix")) })
+
+	serve(m, "GET", "/api/users")
+	if got := serve(m, "GET", "/api/users").Body.String(); got != "prefix" {
+		t.Fatalf("got %q", got)
+	}
+
+	// A more specific pattern must take over the cached path.
+	m.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("exact")) })
+	if got := serve(m, "GET", "/api/users").Body.String(); got != "exact" {
+		t.Fatalf("stale resolution served: %q", got)
+	}
+	if got := serve(m, "GET", "/api/users").Body.String(); got != "exact" {
+		t.Fatalf("cached resolution wrong: %q", got)
+	}
+}
+
+func TestMuxHostPatterns(t *testing.T) {
+	m := newTestMux(t)
+	m.HandleFunc("a.example/x", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("a")) })
+	m.HandleFunc("/x", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("any")) })
+
+	for i := 0; i < 2; i++ {
+		if got := serve(m, "GET", "http://a.example/x").Body.String(); got != "a" {
+			t.Fatalf("host route: %q", got)
+		}
+		if got := serve(m, "GET", "http://b.example/x").Body.String(); got != "any" {
+			t.Fatalf("fallback route: %q", got)
+		}
+	}
+}
+
+func TestMuxesShareCache(t *testing.T) {
+	cache := liteLRU.NewLRUCache(256, 16)
+	t.Cleanup(cache.Close)
+	reply := func(body string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+	}
+
+	// Route indexes differ between the two Muxes.
+	a, b := New(cache), New(cache)
+	a.HandleFunc("/y", reply("a/y"))
+	a.HandleFunc("/x", reply("a/x"))
+	b.HandleFunc("/x", reply("b/x"))
+	b.HandleFunc("/y", reply("b/y"))
+
+	for i := 0; i < 2; i++ {
+		if got := serve(a, "GET", "/x").Body.String(); got != "a/x" {
+			t.Fatalf("request %d to a: %q", i, got)
+		}
+		if got := serve(b, "GET", "/x").Body.String(); got != "b/x" {
+			t.Fatalf("request %d to b: %q", i, got)
+		}
+	}
+}
+
+func TestWildcards(t *testing.T) {
+	got := wildcards("GET example.com/{a}/x/{b...}/{$}")
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("wildcards = %v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package liteLRU
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// Namespace is a tenant-scoped handle onto an LRUCache. Namespaces share the
+// cache's chunk arrays and slots, but their key spaces are disjoint and the
+// number of slots each may occupy is capped by its quota: once a namespace is
+// at quota, inserting a new entry evicts one of the namespace's own entries
+// instead of another tenant's. Entries added through the cache itself belong
+// to no namespace and are not subject to any quota.
+type Namespace struct {
+	c         *LRUCache
+	name      string
+	id        uint32
+	quota     atomic.Int64
+	count     atomic.Int64  // valid slots currently owned
+	hand      atomic.Uint32 // next chunk to search for an own entry to evict
+	last      atomic.Uint32 // chunk of the namespace's latest insert
+	evictions atomic.Int64  // own entries evicted to stay within quota
+	stats     statStripes   // 8 stripes
+}
+
+// Namespace returns the handle for name, creating it on first use. quota is
+// the maximum number of entries the namespace may hold; values <= 0 or above
+// the cache capacity mean the full capacity. Calling Namespace again with the
+// same name returns the same handle and updates its quota.
+func (c *LRUCache) Namespace(name string, quota int) *Namespace {
+	if quota <= 0 || quota > int(c.capacity) {
+		quota = int(c.capacity)
+	}
+	for {
+		old := c.spaces.Load()
+		var list []*Namespace
+		if old != nil {
+			list = *old
+		}
+		for _, ns := range list {
+			if ns.name == name {
+				ns.quota.Store(int64(quota))
+				return ns
+			}
+		}
+
+		ns := &Namespace{c: c, name: name, id: uint32(len(list) + 1), stats: newStatStripes(8, c.line)}
+		ns.quota.Store(int64(quota))
+		next := append(list[:len(list):len(list)], ns)
+		if c.spaces.CompareAndSwap(old, &next) {
+			return ns
+		}
+	}
+}
+
+// namespace resolves a slot owner id to its handle (nil for id 0).
+func (c *LRUCache) namespace(id uint32) *Namespace {
+	if id == 0 {
+		return nil
+	}
+	list := c.spaces.Load()
+	if list == nil || int(id) > len(*list) {
+		return nil
+	}
+	return (*list)[id-1]
+}
+
+// disown gives back the namespace occupancy of slot idx, which the caller is
+// about to invalidate or overwrite while owning its writing bit.
+func (c *LRUCache) disown(idx uint32) {
+	if ns := c.namespace(c.state(idx).ns.Load()); ns != nil {
+		ns.count.Add(-1)
+	}
+}
+
+// ident returns the namespace id stamped into slots, 0 for a nil handle.
+func (n *Namespace) ident() uint32 {
+	if n == nil {
+		return 0
+	}
+	return n.id
+}
+
+// Name returns the namespace name.
+func (n *Namespace) Name() string { return n.name }
+
+// Quota returns the maximum number of entries the namespace may hold.
+func (n *Namespace) Quota() int { return int(n.quota.Load()) }
+
+// Len returns the number of entries the namespace currently occupies. Entries
+// dropped by InvalidateAll keep counting, and keep counting against the
+// quota, until a writer or makeRoom next touches their chunk.
+func (n *Namespace) Len() int { return int(n.count.Load()) }
+
+// Add adds or updates an entry in the namespace, evicting one of the
+// namespace's own entries if it is at quota.
+func (n *Namespace) Add(method, path string, handler HandlerFunc, params []Param) {
+	n.c.add(method, path, handler, params, &entryMeta{ns: n.id})
+}
+
+// Get retrieves an entry of the namespace; see LRUCache.Get.
+func (n *Namespace) Get(method, path string, dst []Param) (HandlerFunc, []Param, bool) {
+	handler, params, _, res := n.c.get(method, path, nil, dst, n)
+	return handler, params, res == Hit
+}
+
+// AddNotFound caches a negative entry in the namespace; see LRUCache.AddNotFound.
+func (n *Namespace) AddNotFound(method, path string, status int) {
+	n.c.add(method, path, nil, nil, &entryMeta{ns: n.id, status: negativeStatus(status)})
+}
+
+// Lookup retrieves an entry of the namespace; see LRUCache.Lookup.
+func (n *Namespace) Lookup(method, path string, dst []Param) (HandlerFunc, []Param, int, Result) {
+	return n.c.get(method, path, nil, dst, n)
+}
+
+// Stats returns the namespace's hit/miss/drop statistics. Hits and misses are
+// also counted in the cache-wide Stats.
+func (n *Namespace) Stats() (hits, misses, drops int64, ratio float64) {
+	for i := uint64(0); i < 8; i++ {
+		hits += n.stats.at(i).hits.Load()
+		misses += n.stats.at(i).misses.Load()
+		drops += n.stats.at(i).drops.Load()
+	}
+	total := hits + misses
+	if total > 0 {
+		ratio = float64(hits) / float64(total)
+	}
+	return
+}
+
+// Evictions returns how many of the namespace's own entries were evicted to
+// keep it within its quota.
+func (n *Namespace) Evictions() int64 { return n.evictions.Load() }
+
+// roomProbes bounds the number of chunks makeRoom walks per insert, so that
+// a namespace with a small quota in a large cache does not pay for a scan of
+// the whole cache on every insert.
+const roomProbes = 8
+
+// makeRoom evicts one of the namespace's own entries. If the target set holds
+// one, its slot is claimed and returned so the new entry simply replaces it.
+// Otherwise it tries the chunk of the namespace's latest insert, then lets the
+// clock hand walk up to roomProbes further chunks; the first own entry found
+// is evicted and 0xFFFFFFFF returned so the caller picks a victim in the
+// target set as usual. The same happens when a chunk searched on the way
+// turns out to hold entries dropped by InvalidateAll, whose release brings
+// the namespace back under quota. ok is false if no own entry was found, in which case
+// the insert is shed so that the namespace stays within its quota; the hand
+// has moved on, so later inserts search elsewhere.
+func (n *Namespace) makeRoom(group uint32) (idx uint32, ok bool) {
+	c := n.c
+	first, span, mask := c.span(group)
+	for k := first; k < first+span; k++ {
+		if idx, ok := n.claimOwn(k, mask); ok {
+			n.evictions.Add(1)
+			return idx, true
+		}
+	}
+	if n.count.Load() < n.quota.Load() {
+		return 0xFFFFFFFF, true
+	}
+	try := func(k uint32) bool {
+		if k >= first && k < first+span {
+			return false
+		}
+		idx, ok := n.claimOwn(k, ^uint64(0))
+		if !ok {
+			return n.count.Load() < n.quota.Load()
+		}
+		chk := &c.chunks[k]
+		c.clearSlot(chk, idx, idx%64)
+		releaseSlot(chk, idx%64)
+		n.evictions.Add(1)
+		return true
+	}
+	if try(n.last.Load()) {
+		return 0xFFFFFFFF, true
+	}
+	for i := 0; i < roomProbes; i++ {
+		if try(n.hand.Add(1) % c.numGroups) {
+			return 0xFFFFFFFF, true
+		}
+	}
+	return 0xFFFFFFFF, false
+}
+
+// claimOwn claims the writing bit of a current valid slot among the mask bits
+// of chunk k owned by the namespace, preferring slots whose CLOCK reference
+// bit is clear. Like findVictim it reconciles the chunk first, so the slot can
+// be rewritten in place and the chunk's valid bits stay exact.
+func (n *Namespace) claimOwn(k uint32, mask uint64) (uint32, bool) {
+	c := n.c
+	chk := &c.chunks[k]
+	gen := c.gen.Load()
+	if chk.gen.Load() != gen {
+		c.reconcile(chk, k, gen)
+	}
+
+	var own uint64
+	for m := chk.valid.Load() & ^chk.writing.Load() & mask; m != 0; m &= m - 1 {
+		bit := uint32(bits.TrailingZeros64(m))
+		if n.owns(c.state(k*64+bit), gen) {
+			own |= 1 << bit
+		}
+	}
+	if own == 0 {
+		return 0, false
+	}
+	if cold := own & ^chk.accessed.Load(); cold != 0 {
+		own = cold
+	}
+
+	for m := own; m != 0; m &= m - 1 {
+		bit := uint32(bits.TrailingZeros64(m))
+		if !claimSlot(chk, bit) {
+			continue
+		}
+		idx := k*64 + bit
+		if chk.valid.Load()&(1<<bit) != 0 && n.owns(c.state(idx), gen) {
+			return idx, true
+		}
+		releaseSlot(chk, bit)
+	}
+	return 0, false
+}
+
+// owns reports whether st holds an entry of the namespace from generation gen.
+func (n *Namespace) owns(st *slotState, gen uint32) bool {
+	return st.ns.Load() == n.id && st.gen.Load() == gen
+}
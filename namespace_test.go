@@ -0,0 +1,139 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNamespaceIsolation(t *testing.T) {
+	c := NewLRUCache(256, 10)
+	defer c.Close()
+
+	a := c.Namespace("tenant-a", 0)
+	b := c.Namespace("tenant-b", 0)
+	if c.Namespace("tenant-a", 0) != a {
+		t.Fatal("Namespace should return the existing handle")
+	}
+
+	a.Add("GET", "/x", nil, []Param{{Key: "owner", Value: "a"}})
+	b.Add("GET", "/x", nil, []Param{{Key: "owner", Value: "b"}})
+
+	if _, p, ok := a.Get("GET", "/x", nil); !ok || p[0].Value != "a" {
+		t.Fatalf("tenant-a read %v %+v", ok, p)
+	}
+	if _, p, ok := b.Get("GET", "/x", nil); !ok || p[0].Value != "b" {
+		t.Fatalf("tenant-b read %v %+v", ok, p)
+	}
+	if _, _, ok := c.Get("GET", "/x", nil); ok {
+		t.Fatal("shared key space must not see namespaced entries")
+	}
+	if a.Len() != 1 || b.Len() != 1 {
+		t.Fatalf("occupancy a=%d b=%d", a.Len(), b.Len())
+	}
+}
+
+func TestNamespaceQuota(t *testing.T) {
+	c := NewLRUCache(1024, 10)
+	defer c.Close()
+
+	quiet := c.Namespace("quiet", 0)
+	for i := 0; i < 200; i++ {
+		quiet.Add("GET", fmt.Sprintf("/quiet/%d", i), nil, nil)
+	}
+
+	noisy := c.Namespace("noisy", 100)
+	for i := 0; i < 10000; i++ {
+		noisy.Add("GET", fmt.Sprintf("/noisy/%d", i), nil, nil)
+	}
+
+	if n := noisy.Len(); n > noisy.Quota() {
+		t.Fatalf("noisy namespace holds %d entries, quota %d", n, noisy.Quota())
+	}
+	if noisy.Evictions() == 0 {
+		t.Fatal("expected quota evictions")
+	}
+	lost := 0
+	for i := 0; i < 200; i++ {
+		if _, _, ok := quiet.Get("GET", fmt.Sprintf("/quiet/%d", i), nil); !ok {
+			lost++
+		}
+	}
+	// The noisy tenant may still displace quiet entries that share a set with
+	// its own, but only a fraction of them.
+	if lost > 40 {
+		t.Fatalf("noisy tenant evicted %d/200 quiet entries", lost)
+	}
+	if _, misses, _, _ := quiet.Stats(); misses != int64(lost) {
+		t.Fatalf("quiet misses %d, lost %d", misses, lost)
+	}
+}
+
+func TestNamespaceOccupancyTracksInvalidation(t *testing.T) {
+	c := NewLRUCache(128, 10)
+	defer c.Close()
+
+	ns := c.Namespace("t", 0)
+	for i := 0; i < 50; i++ {
+		ns.Add("GET", fmt.Sprintf("/%d", i), nil, nil)
+	}
+	c.Clear()
+	if ns.Len() != 0 {
+		t.Fatalf("occupancy %d after Clear", ns.Len())
+	}
+}
+
+func TestNamespaceQuotaAfterInvalidateAll(t *testing.T) {
+	c := NewLRUCache(64, 10) // a single set
+	defer c.Close()
+
+	ns := c.Namespace("a", 1)
+	ns.Add("GET", "/1", nil, nil)
+	c.InvalidateAll()
+	ns.Add("GET", "/2", nil, nil)
+
+	if _, _, ok := ns.Get("GET", "/2", nil); !ok {
+		t.Fatal("entry added after InvalidateAll missing")
+	}
+	if _, _, ok := ns.Get("GET", "/1", nil); ok {
+		t.Fatal("invalidated entry served")
+	}
+	if c.Len() != 1 || ns.Len() != 1 {
+		t.Fatalf("Len = %d, namespace Len = %d, want 1 and 1", c.Len(), ns.Len())
+	}
+}
+
+func TestNamespaceSmallQuotaLargeCache(t *testing.T) {
+	c := NewLRUCache(1<<16, 10)
+	defer c.Close()
+
+	ns := c.Namespace("small", 4)
+	const n = 5000
+	for i := 0; i < n; i++ {
+		ns.Add("GET", fmt.Sprintf("/small/%d", i), nil, nil)
+	}
+	if ns.Len() > ns.Quota() {
+		t.Fatalf("namespace holds %d entries, quota %d", ns.Len(), ns.Quota())
+	}
+	// Own entries are found through the latest-insert hint rather than by
+	// walking the cache's 1024 chunks, so inserts are rarely shed.
+	if _, _, drops, _ := ns.Stats(); drops > n/100 {
+		t.Fatalf("%d of %d inserts shed", drops, n)
+	}
+	if _, _, ok := ns.Get("GET", fmt.Sprintf("/small/%d", n-1), nil); !ok {
+		t.Fatal("latest insert missing")
+	}
+}
+
+func BenchmarkNamespaceSmallQuota(b *testing.B) {
+	c := NewLRUCache(1<<20, 10)
+	defer c.Close()
+	ns := c.Namespace("small", 16)
+	paths := make([]string, 4096)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/small/%d", i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ns.Add("GET", paths[i&4095], nil, nil)
+	}
+}
@@ -0,0 +1,61 @@
+package liteLRU
+
+// Result classifies the outcome of Lookup.
+type Result uint8
+
+const (
+	// Miss means the route is not cached, or only an expired negative entry is.
+	Miss Result = iota
+	// Hit means a regular entry was found.
+	Hit
+	// NegativeHit means the route is cached as unmatched: AddNotFound recorded
+	// a status for it that has not yet expired.
+	NegativeHit
+)
+
+// String returns the name of the result.
+func (r Result) String() string {
+	switch r {
+	case Hit:
+		return "Hit"
+	case NegativeHit:
+		return "NegativeHit"
+	default:
+		return "Miss"
+	}
+}
+
+// AddNotFound caches the fact that method and path have no handler, so the
+// router can skip rediscovering it. status is the response to give instead,
+// typically 404 or 405; values <= 0 store 404. Negative entries expire after
+// the TTL set with WithNegativeTTL, occupy a slot like any other entry and are
+// replaced by a later Add of the same route.
+func (c *LRUCache) AddNotFound(method, path string, status int) {
+	c.add(method, path, nil, nil, &entryMeta{status: negativeStatus(status)})
+}
+
+// Lookup is like Get but distinguishes a cached negative answer from a miss.
+// For a NegativeHit it returns the status recorded by AddNotFound; for a Hit
+// the status is 0.
+func (c *LRUCache) Lookup(method, path string, dst []Param) (HandlerFunc, []Param, int, Result) {
+	return c.get(method, path, nil, dst, nil)
+}
+
+// NegativeStats returns how many lookups were answered by a negative entry
+// and how many found one that had already expired. Neither is included in the
+// hits and misses reported by Stats; expired lookups also count as misses.
+func (c *LRUCache) NegativeStats() (hits, expired int64) {
+	for i := uint64(0); i < 64; i++ {
+		hits += c.stats.at(i).negHits.Load()
+		expired += c.stats.at(i).negExpired.Load()
+	}
+	return
+}
+
+// negativeStatus normalises the status stored for a negative entry.
+func negativeStatus(status int) uint32 {
+	if status <= 0 {
+		return 404 // http.StatusNotFound
+	}
+	return uint32(status)
+}
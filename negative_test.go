@@ -0,0 +1,71 @@
+package liteLRU
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNegativeEntries(t *testing.T) {
+	c := NewLRUCache(256, 10, WithNegativeTTL(time.Second))
+	defer c.Close()
+	var clock atomic.Int64
+	c.now = clock.Load
+
+	c.AddNotFound("GET", "/wp-login.php", 404)
+	c.AddNotFound("DELETE", "/users", 405)
+	c.AddNotFound("GET", "/zero", 0)
+
+	if _, _, status, res := c.Lookup("GET", "/wp-login.php", nil); res != NegativeHit || status != 404 {
+		t.Fatalf("Lookup = %d %v", status, res)
+	}
+	if _, _, status, res := c.Lookup("DELETE", "/users", nil); res != NegativeHit || status != 405 {
+		t.Fatalf("Lookup = %d %v", status, res)
+	}
+	if _, _, status, _ := c.Lookup("GET", "/zero", nil); status != 404 {
+		t.Fatalf("default negative status = %d", status)
+	}
+	if _, _, status, res := c.Lookup("GET", "/never", nil); res != Miss || status != 0 {
+		t.Fatalf("uncached Lookup = %d %v", status, res)
+	}
+	if _, _, ok := c.Get("GET", "/wp-login.php", nil); ok {
+		t.Fatal("Get must not report a negative entry as found")
+	}
+
+	hits, misses, _, _ := c.Stats()
+	negHits, expired := c.NegativeStats()
+	if hits != 0 || misses != 1 || negHits != 4 || expired != 0 {
+		t.Fatalf("stats hits=%d misses=%d negHits=%d expired=%d", hits, misses, negHits, expired)
+	}
+
+	clock.Store(int64(2 * time.Second))
+	if _, _, _, res := c.Lookup("GET", "/wp-login.php", nil); res != Miss {
+		t.Fatalf("expired negative entry returned %v", res)
+	}
+	if _, expired = c.NegativeStats(); expired != 1 {
+		t.Fatalf("expired = %d", expired)
+	}
+}
+
+func TestAddReplacesNegativeEntry(t *testing.T) {
+	c := NewLRUCache(256, 10)
+	defer c.Close()
+
+	c.AddNotFound("GET", "/late", 404)
+	c.Add("GET", "/late", nil, []Param{{Key: "k", Value: "v"}})
+	if _, p, status, res := c.Lookup("GET", "/late", nil); res != Hit || status != 0 || p[0].Value != "v" {
+		t.Fatalf("Lookup after Add = %+v %d %v", p, status, res)
+	}
+	if c.View("GET", "/late", func(HandlerFunc, []Param) {}) != true {
+		t.Fatal("View should see the replacing entry")
+	}
+
+	ns := c.Namespace("tenant", 0)
+	ns.AddNotFound("GET", "/late", 410)
+	if _, _, status, res := ns.Lookup("GET", "/late", nil); res != NegativeHit || status != 410 {
+		t.Fatalf("namespace Lookup = %d %v", status, res)
+	}
+	if _, _, _, res := c.Lookup("GET", "/late", nil); res != Hit {
+		t.Fatal("namespaced negative entry leaked into the shared key space")
+	}
+}
@@ -0,0 +1,268 @@
+package liteLRU
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// KeyNormalizer rewrites a route key before it is hashed and compared, so that
+// equivalent spellings of a route share one entry. A normalizer must return
+// its inputs unchanged when they are already normal; the built-in ones then
+// do not allocate. Normalizers see the path including any query string, and
+// are called with an empty method by Methods and GetAnyMethod.
+type KeyNormalizer func(method, path string) (string, string)
+
+// WithKeyNormalizer normalizes every key passed to the cache through the given
+// normalizers, in order, in Add, Get and every other method taking a key.
+// Using the option more than once appends to the chain.
+func WithKeyNormalizer(normalizers ...KeyNormalizer) Option {
+	return func(cfg *config) {
+		cfg.normalizers = append(cfg.normalizers, normalizers...)
+	}
+}
+
+// chainNormalizers composes normalizers into one, or returns nil for none.
+func chainNormalizers(normalizers []KeyNormalizer) KeyNormalizer {
+	switch len(normalizers) {
+	case 0:
+		return nil
+	case 1:
+		return normalizers[0]
+	}
+	return func(method, path string) (string, string) {
+		for _, n := range normalizers {
+			method, path = n(method, path)
+		}
+		return method, path
+	}
+}
+
+// normalizeKey applies the configured normalizer, if any.
+func (c *LRUCache) normalizeKey(method, path string) (string, string) {
+	if c.normalize == nil {
+		return method, path
+	}
+	return c.normalize(method, path)
+}
+
+// splitQuery splits a key path into its path and query ("?..." or "").
+func splitQuery(p string) (string, string) {
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		return p[:i], p[i:]
+	}
+	return p, ""
+}
+
+// TrimTrailingSlash removes trailing slashes from the path, except from the
+// root path "/", so "/users/1/" and "/users/1" share an entry.
+func TrimTrailingSlash(method, p string) (string, string) {
+	base, query := splitQuery(p)
+	if len(base) < 2 || base[len(base)-1] != '/' {
+		return method, p
+	}
+	trimmed := strings.TrimRight(base, "/")
+	if trimmed == "" {
+		trimmed = "/"
+	}
+	return method, trimmed + query
+}
+
+// LowercasePath folds ASCII upper-case letters in the path (not the query) to
+// lower case, so "/Users/1" and "/users/1" share an entry. Percent-encoded
+// upper-case letters are folded too ("%4A" becomes "%6A") and the hex digits
+// of other escapes are left alone, so the result is the same whether it runs
+// before or after DecodePercent.
+func LowercasePath(method, p string) (string, string) {
+	base, query := splitQuery(p)
+	i := 0
+	for ; i < len(base); i++ {
+		if isUpper(base[i]) {
+			break
+		}
+		if isEscape(base, i) {
+			if isUpper(escaped(base, i)) {
+				break
+			}
+			i += 2
+		}
+	}
+	if i == len(base) {
+		return method, p
+	}
+
+	var b strings.Builder
+	b.Grow(len(p))
+	b.WriteString(base[:i])
+	for ; i < len(base); i++ {
+		switch c := base[i]; {
+		case isUpper(c):
+			b.WriteByte(c + 'a' - 'A')
+		case isEscape(base, i):
+			if e := escaped(base, i); isUpper(e) {
+				e += 'a' - 'A'
+				b.WriteByte('%')
+				b.WriteByte("0123456789ABCDEF"[e>>4])
+				b.WriteByte("0123456789ABCDEF"[e&0xF])
+			} else {
+				b.WriteString(base[i : i+3])
+			}
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString(query)
+	return method, b.String()
+}
+
+func isUpper(c byte) bool { return 'A' <= c && c <= 'Z' }
+
+// isEscape reports whether p has a well-formed percent escape at i.
+func isEscape(p string, i int) bool {
+	return p[i] == '%' && i+2 < len(p) && isHex(p[i+1]) && isHex(p[i+2])
+}
+
+// escaped returns the byte encoded by the escape at p[i].
+func escaped(p string, i int) byte {
+	return unhex(p[i+1])<<4 | unhex(p[i+2])
+}
+
+// DecodePercent decodes percent-encoded unreserved characters (letters,
+// digits, '-', '.', '_' and '~'), which never change the meaning of a URI,
+// and upper-cases the hex digits of the escapes it keeps, so "/users/%31"
+// and "/users/1" share an entry. Malformed escapes are left as they are.
+func DecodePercent(method, p string) (string, string) {
+	i := 0
+	for ; i < len(p); i++ {
+		if p[i] == '%' && i+2 < len(p) && escapeChanges(p[i+1], p[i+2]) {
+			break
+		}
+	}
+	if i == len(p) {
+		return method, p
+	}
+
+	var b strings.Builder
+	b.Grow(len(p))
+	b.WriteString(p[:i])
+	for ; i < len(p); i++ {
+		if p[i] != '%' || i+2 >= len(p) || !isHex(p[i+1]) || !isHex(p[i+2]) {
+			b.WriteByte(p[i])
+			continue
+		}
+		if c := unhex(p[i+1])<<4 | unhex(p[i+2]); isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(upperHex(p[i+1]))
+			b.WriteByte(upperHex(p[i+2]))
+		}
+		i += 2
+	}
+	return method, b.String()
+}
+
+// escapeChanges reports whether DecodePercent rewrites the escape %hl.
+func escapeChanges(h, l byte) bool {
+	if !isHex(h) || !isHex(l) {
+		return false
+	}
+	return isUnreserved(unhex(h)<<4|unhex(l)) || upperHex(h) != h || upperHex(l) != l
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c >= 'a':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func upperHex(c byte) byte {
+	if 'a' <= c && c <= 'f' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// CleanDotSegments resolves "." and ".." segments and collapses repeated
+// slashes in the path, like path.Clean but keeping a trailing slash, so
+// "/a/./b//c/../d" and "/a/b/d" share an entry.
+func CleanDotSegments(method, p string) (string, string) {
+	base, query := splitQuery(p)
+	if !needsClean(base) {
+		return method, p
+	}
+	cleaned := path.Clean(base)
+	if strings.HasSuffix(base, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return method, cleaned + query
+}
+
+// needsClean reports whether base has an empty, "." or ".." segment.
+func needsClean(base string) bool {
+	for i := 0; i < len(base); i++ {
+		if base[i] != '/' || i+1 == len(base) {
+			continue
+		}
+		rest := base[i+1:]
+		if rest[0] == '/' {
+			return true
+		}
+		seg := rest
+		if j := strings.IndexByte(rest, '/'); j >= 0 {
+			seg = rest[:j]
+		}
+		if seg == "." || seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// SortQuery sorts the parameters of the query string by name, keeping their
+// encoding and the relative order of repeated names, so "/s?b=2&a=1" and
+// "/s?a=1&b=2" share an entry.
+func SortQuery(method, p string) (string, string) {
+	base, query := splitQuery(p)
+	if len(query) < 2 {
+		return method, p
+	}
+	sorted := true
+	prev := ""
+	for rest := query[1:]; rest != ""; {
+		var pair string
+		pair, rest, _ = strings.Cut(rest, "&")
+		name, _, _ := strings.Cut(pair, "=")
+		if name < prev {
+			sorted = false
+			break
+		}
+		prev = name
+	}
+	if sorted {
+		return method, p
+	}
+
+	pairs := strings.Split(query[1:], "&")
+	slices.SortStableFunc(pairs, func(a, b string) int {
+		an, _, _ := strings.Cut(a, "=")
+		bn, _, _ := strings.Cut(b, "=")
+		return strings.Compare(an, bn)
+	})
+	return method, base + "?" + strings.Join(pairs, "&")
+}
@@ -0,0 +1,72 @@
+package liteLRU
+
+import "testing"
+
+func TestBuiltinNormalizers(t *testing.T) {
+	cases := []struct {
+		name string
+		n    KeyNormalizer
+		in   string
+		want string
+	}{
+		{"trim", TrimTrailingSlash, "/users/1/", "/users/1"},
+		{"trim multiple", TrimTrailingSlash, "/users/1//?x=1", "/users/1?x=1"},
+		{"trim root", TrimTrailingSlash, "/", "/"},
+		{"lower", LowercasePath, "/Users/1?Q=A", "/users/1?Q=A"},
+		{"lower escapes", LowercasePath, "/Users/%4a%2F%2f%41?Q=%41", "/users/%6A%2F%2f%61?Q=%41"},
+		{"decode unreserved", DecodePercent, "/users/%31%7e", "/users/1~"},
+		{"keep reserved", DecodePercent, "/a%2fb%2F", "/a%2Fb%2F"},
+		{"malformed escape", DecodePercent, "/a%zz%4", "/a%zz%4"},
+		{"clean", CleanDotSegments, "/a/./b//c/../d", "/a/b/d"},
+		{"clean keeps slash", CleanDotSegments, "/a/b/../?q", "/a/?q"},
+		{"clean dotfile", CleanDotSegments, "/.well-known/x", "/.well-known/x"},
+		{"sort", SortQuery, "/s?b=2&a=1&b=1", "/s?a=1&b=2&b=1"},
+		{"sorted", SortQuery, "/s?a=1&b=2", "/s?a=1&b=2"},
+	}
+	for _, tc := range cases {
+		if m, got := tc.n("GET", tc.in); got != tc.want || m != "GET" {
+			t.Errorf("%s: %q -> %q %q, want %q", tc.name, tc.in, m, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizersDoNotAllocateWhenNormal(t *testing.T) {
+	chain := chainNormalizers([]KeyNormalizer{TrimTrailingSlash, LowercasePath, DecodePercent, CleanDotSegments, SortQuery})
+	allocs := testing.AllocsPerRun(100, func() {
+		chain("GET", "/api/v1/users/42/profile?a=1&b=%2F")
+	})
+	if allocs != 0 {
+		t.Fatalf("normalizing a normal key allocated %v times", allocs)
+	}
+}
+
+func TestCacheNormalizesKeys(t *testing.T) {
+	c := NewLRUCache(256, 10, WithKeyNormalizer(TrimTrailingSlash, LowercasePath), WithKeyNormalizer(DecodePercent))
+	defer c.Close()
+
+	c.Add("GET", "/users/1", nil, []Param{{Key: "id", Value: "1"}})
+	for _, p := range []string{"/users/1", "/users/1/", "/Users/1", "/users/%31"} {
+		if _, params, ok := c.Get("GET", p, nil); !ok || params[0].Value != "1" {
+			t.Fatalf("Get(%q) = %v %+v", p, ok, params)
+		}
+	}
+	// Decoding after case folding must not reintroduce upper case.
+	c.Add("GET", "/users/j", nil, nil)
+	for _, p := range []string{"/Users/%4A", "/users/%4a", "/users/%6A"} {
+		if _, _, ok := c.Get("GET", p, nil); !ok {
+			t.Fatalf("Get(%q) missed /users/j", p)
+		}
+	}
+	c.Add("POST", "/USERS/1/", nil, nil)
+	if got := c.Methods("/users/%31/"); len(got) != 2 {
+		t.Fatalf("Methods = %v", got)
+	}
+
+	dst := make([]Param, 0, 4)
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Get("GET", "/users/1", dst)
+	})
+	if allocs != 0 {
+		t.Fatalf("Get of a normal key allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,108 @@
+package liteLRU
+
+import "time"
+
+// Option configures optional behaviour of an LRUCache at construction time.
+// The zero set of options yields the default heap-backed SoA layout.
+type Option func(*config)
+
+// config collects the construction-time settings applied by Option values.
+type config struct {
+	arena      bool
+	arenaBytes int
+
+	load         Loader
+	freshFor     time.Duration
+	refreshAhead time.Duration
+
+	negativeTTL time.Duration
+
+	normalizers []KeyNormalizer
+
+	keyExtras int
+
+	values bool
+
+	twoChoice bool
+
+	victims int
+
+	ways int
+
+	sig16 bool
+
+	fnv bool
+
+	compact bool
+
+	lineSize int
+
+	hugePages bool
+	prefault  bool
+	mlock     bool
+
+	shared *sharedConfig // set by OpenShared
+
+	pressure *MemoryPressure
+}
+
+// WithArena stores method, path and param bytes in an off-heap mmap-backed
+// slab arena instead of the GC-scanned methods/paths/params SoA arrays. Each
+// slot then holds only an offset into the arena, so the key and param data of
+// a large cache contributes nothing to GC mark time. Handlers remain on the
+// Go heap because they may be closures.
+//
+// size is the arena capacity in bytes; values <= 0 select 256 bytes per slot.
+// Entries that do not fit in the arena are dropped and counted as drops.
+//
+// Get, Peek and GetAnyMethod copy the param bytes out of the arena, in one
+// allocation per call, so the strings they return are the caller's to keep.
+// Only View lends params that alias arena memory, and only while fn runs.
+func WithArena(size int) Option {
+	return func(cfg *config) {
+		cfg.arena = true
+		cfg.arenaBytes = size
+	}
+}
+
+// WithWays sets the associativity of the cache: the number of slots a key
+// may occupy and that a lookup scans. Fewer ways mean shorter scans and
+// cheaper victim searches for small caches; more ways mean fewer conflict
+// misses for large ones. n is rounded up to a power of two and clamped to
+// 16..128; values <= 0 select the default of 64. At 128 ways a set spans two
+// chunks, and the capacity is raised to at least one set.
+func WithWays(n int) Option {
+	return func(cfg *config) {
+		if n <= 0 {
+			n = 64
+		}
+		cfg.ways = min(max(nextPowerOfTwo(n), 16), 128)
+	}
+}
+
+// WithLoader gives every entry a soft freshness deadline of freshFor after it
+// is written and lets the cache refresh entries through load. A Get that hits
+// an entry past its deadline still returns the stale value but starts one
+// background call to load, whose result replaces the entry; concurrent hits
+// do not start further calls while it runs. Hits within refreshAhead of the
+// deadline start the same refresh early, so hot entries are normally reloaded
+// before they ever go stale. A failed refresh keeps the stale entry and is
+// retried on a later hit.
+//
+// Entries are never expired by the deadline alone; they leave the cache only
+// by eviction or invalidation. Load uses load to fill misses as well.
+func WithLoader(load Loader, freshFor, refreshAhead time.Duration) Option {
+	return func(cfg *config) {
+		cfg.load = load
+		cfg.freshFor = freshFor
+		cfg.refreshAhead = refreshAhead
+	}
+}
+
+// WithNegativeTTL sets how long entries added by AddNotFound are served as
+// negative hits. Values <= 0 select the default of 5 seconds.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(cfg *config) {
+		cfg.negativeTTL = ttl
+	}
+}
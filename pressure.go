@@ -0,0 +1,283 @@
+package liteLRU
+
+import (
+	"io/fs"
+	"math"
+	"math/bits"
+	"path"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Footprint is the memory held by a cache, in bytes.
+type Footprint struct {
+	// OffHeap counts the mmap slabs: seqlocks, chunks and the arena, or the
+	// shared mapping. The Go runtime, and so GOMEMLIMIT, does not see them.
+	OffHeap int64
+	// Resident is the part of OffHeap backed by memory. Slab pages cost
+	// nothing until first written, and once written they stay resident
+	// until Close. It is only measured on Linux and equals OffHeap elsewhere.
+	Resident int64
+	// Heap counts the Go heap arrays sized by the capacity. Entry data
+	// referenced from them (params, compact records, values, handler
+	// closures) comes on top and shrinks with the entry count.
+	Heap int64
+}
+
+// Footprint returns the memory the cache holds.
+func (c *LRUCache) Footprint() Footprint {
+	var f Footprint
+	if c.shared != nil {
+		f.OffHeap = int64(len(c.shared.mem))
+		f.Resident = residentBytes(c.shared.mem)
+	} else {
+		f.OffHeap = int64(len(c.states) + len(c.chunks)*int(unsafe.Sizeof(chunk{})))
+		f.Resident = f.OffHeap
+		slabs := [][]byte{c.statesSlab, c.chunksSlab}
+		if c.arena != nil {
+			f.OffHeap += int64(len(c.arena.slab))
+			f.Resident += int64(len(c.arena.slab))
+			slabs = append(slabs, c.arena.slab)
+		}
+		// Slabs that fell back to the Go heap count in full.
+		for _, slab := range slabs {
+			if slab != nil {
+				f.Resident += residentBytes(slab) - int64(len(slab))
+			}
+		}
+	}
+	str := int64(unsafe.Sizeof(atomicString{}))
+	f.Heap = int64(len(c.methods)+len(c.paths))*str +
+		int64(len(c.extras))*int64(c.capacity)*str +
+		int64(len(c.params))*int64(unsafe.Sizeof(atomicSlice{})) +
+		int64(len(c.handlers))*int64(unsafe.Sizeof(atomicHandler{})) +
+		int64(len(c.records)+len(c.values))*int64(unsafe.Sizeof(uintptr(0))) +
+		int64(len(c.stats.mem))
+	return f
+}
+
+// MemoryPressure configures WithMemoryPressure.
+type MemoryPressure struct {
+	// High and Low are fractions of the memory limit. A sample above High
+	// halves the effective associativity, a sample below Low doubles it
+	// again, up to the configured ways. Defaults are 0.9 and 0.75.
+	High, Low float64
+	// MinWays is the smallest effective associativity; the default is an
+	// eighth of the ways, at least 2.
+	MinWays int
+	// Interval is the time between samples; the default is one second.
+	Interval time.Duration
+	// Sample reports memory use and limit in bytes, or ok false to skip the
+	// sample. The default uses whichever is closer to its limit of the cgroup
+	// v2 memory.current against memory.max (on Linux) and the Go runtime's
+	// memory plus the resident part of the cache's off-heap footprint against
+	// GOMEMLIMIT.
+	Sample func() (used, limit uint64, ok bool)
+}
+
+// WithMemoryPressure makes the cache give memory back under pressure. A
+// background goroutine samples memory use every p.Interval; while it is above
+// p.High of the limit, each sample halves the number of ways findVictim may
+// fill in every set and evicts the entries in the others. Once use drops
+// below p.Low the ways are restored one doubling per sample. Close stops the
+// goroutine. EffectiveCapacity reports the current capacity.
+//
+// Only Go heap memory is given back: the params, compact records, values and
+// handler closures of evicted entries become garbage. Their arena blocks go
+// back to the arena's free lists for reuse, and the off-heap slabs stay as
+// resident as they were, so pressure the cache cannot relieve leaves it at
+// p.MinWays without further work.
+func WithMemoryPressure(p MemoryPressure) Option {
+	return func(cfg *config) {
+		cfg.pressure = &p
+	}
+}
+
+// pressureWatch is the goroutine started by WithMemoryPressure.
+type pressureWatch struct {
+	p       MemoryPressure
+	minWays uint32
+	trims   int // samples left that trim the sets after the last shrink
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// watchPressure starts the pressure goroutine of c.
+func (c *LRUCache) watchPressure(p MemoryPressure) {
+	if p.High <= 0 {
+		p.High = 0.9
+	}
+	if p.Low <= 0 || p.Low > p.High {
+		p.Low = min(0.75, p.High)
+	}
+	if p.Interval <= 0 {
+		p.Interval = time.Second
+	}
+	if p.Sample == nil {
+		p.Sample = c.samplePressure
+	}
+	minWays := uint32(max(c.ways/8, 2))
+	if p.MinWays > 0 {
+		minWays = min(uint32(nextPowerOfTwo(p.MinWays)), c.ways)
+	}
+	w := &pressureWatch{p: p, minWays: minWays, stop: make(chan struct{}), done: make(chan struct{})}
+	c.watch = w
+	go func() {
+		defer close(w.done)
+		t := time.NewTicker(p.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-t.C:
+				c.relieve()
+			}
+		}
+	}()
+}
+
+// relieve takes one sample and adjusts the effective associativity.
+func (c *LRUCache) relieve() {
+	w := c.watch
+	used, limit, ok := w.p.Sample()
+	if !ok || limit == 0 {
+		return
+	}
+	ratio := float64(used) / float64(limit)
+	ways := c.effWays.Load()
+	switch {
+	case ratio > w.p.High && ways > w.minWays:
+		c.effWays.Store(max(ways/2, w.minWays))
+		w.trims = 2
+	case ratio < w.p.Low && ways < c.ways:
+		c.effWays.Store(min(ways*2, c.ways))
+		w.trims = 0
+	}
+	// Inserts never fill slots beyond the bound, so the sets only need
+	// trimming after a shrink, and once more on the next sample for inserts
+	// that were already underway when it shrank.
+	if w.trims > 0 {
+		w.trims--
+		c.trim()
+	}
+}
+
+// EffectiveCapacity returns the number of slots inserts may currently fill:
+// the capacity, unless WithMemoryPressure has shrunk it.
+func (c *LRUCache) EffectiveCapacity() int {
+	return int(c.numSets * c.effWays.Load())
+}
+
+// usable returns the part of set group that inserts may fill: like span, but
+// limited to the first effective ways slots of the set.
+func (c *LRUCache) usable(group uint32) (first, n uint32, mask uint64) {
+	e := c.effWays.Load()
+	if e >= c.ways {
+		return c.span(group)
+	}
+	if c.ways >= 64 {
+		first = group * (c.ways / 64)
+		if e >= 64 {
+			return first, e / 64, ^uint64(0)
+		}
+		return first, 1, uint64(1)<<e - 1
+	}
+	base := group * c.ways
+	return base / 64, 1, (uint64(1)<<e - 1) << (base % 64)
+}
+
+// trim evicts every entry outside the usable part of its set, skipping slots
+// that are being written.
+func (c *LRUCache) trim() {
+	for group := uint32(0); group < c.numSets; group++ {
+		first, n, mask := c.span(group)
+		ufirst, un, umask := c.usable(group)
+		for k := first; k < first+n; k++ {
+			keep := uint64(0)
+			if k >= ufirst && k < ufirst+un {
+				keep = umask
+			}
+			chk := &c.chunks[k]
+			for m := chk.valid.Load() & mask &^ keep; m != 0; m &= m - 1 {
+				bit := uint32(bits.TrailingZeros64(m))
+				if claimSlot(chk, bit) {
+					c.clearSlot(chk, k*64+bit, bit)
+					releaseSlot(chk, bit)
+				}
+			}
+		}
+	}
+}
+
+// samplePressure is the default MemoryPressure.Sample.
+func (c *LRUCache) samplePressure() (used, limit uint64, ok bool) {
+	if u, l, cok := cgroupMemory(rootFS()); cok {
+		used, limit, ok = u, l, true
+	}
+	if u, l, gok := c.runtimeMemory(); gok && (!ok || float64(u)/float64(l) > float64(used)/float64(limit)) {
+		used, limit, ok = u, l, true
+	}
+	return used, limit, ok
+}
+
+// runtimeMemory returns the memory GOMEMLIMIT applies to plus the resident
+// part of the cache's off-heap footprint, and GOMEMLIMIT itself. ok is false
+// if it is unset.
+func (c *LRUCache) runtimeMemory() (used, limit uint64, ok bool) {
+	l := debug.SetMemoryLimit(-1)
+	if l <= 0 || l == math.MaxInt64 {
+		return 0, 0, false
+	}
+	s := []metrics.Sample{{Name: "/memory/classes/total:bytes"}, {Name: "/memory/classes/heap/released:bytes"}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 || s[1].Value.Kind() != metrics.KindUint64 {
+		return 0, 0, false
+	}
+	used = s[0].Value.Uint64() - s[1].Value.Uint64() + uint64(c.Footprint().Resident)
+	return used, uint64(l), true
+}
+
+// cgroupMemory reads memory.current and memory.max of the cgroup v2 the
+// process belongs to from fsys, the root file system. Limits are inherited,
+// so the nearest ancestor with a limit applies, together with its usage. ok
+// is false outside a limited cgroup v2.
+func cgroupMemory(fsys fs.FS) (used, limit uint64, ok bool) {
+	if fsys == nil {
+		return 0, 0, false
+	}
+	b, err := fs.ReadFile(fsys, "proc/self/cgroup")
+	if err != nil {
+		return 0, 0, false
+	}
+	var dir string
+	for _, line := range strings.Split(string(b), "\n") {
+		if p, found := strings.CutPrefix(line, "0::"); found {
+			dir = path.Join("sys/fs/cgroup", p)
+			break
+		}
+	}
+	if !strings.HasPrefix(dir, "sys/fs/cgroup") {
+		return 0, 0, false
+	}
+	read := func(name string) (uint64, bool) {
+		b, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return 0, false
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+		return v, err == nil
+	}
+	for ; dir != "sys/fs"; dir = path.Dir(dir) {
+		if l, lok := read("memory.max"); lok {
+			if u, uok := read("memory.current"); uok {
+				return u, l, true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package liteLRU
+
+import (
+	"io/fs"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// rootFS returns the root file system, for reading cgroup memory limits.
+func rootFS() fs.FS {
+	return os.DirFS("/")
+}
+
+// residentBytes returns how many bytes of the mapping slab are resident, as
+// reported by mincore, or len(slab) if that fails.
+func residentBytes(slab []byte) int64 {
+	if len(slab) == 0 {
+		return 0
+	}
+	page := os.Getpagesize()
+	vec := make([]byte, (len(slab)+page-1)/page)
+	_, _, errno := unix.Syscall(unix.SYS_MINCORE, uintptr(unsafe.Pointer(&slab[0])), uintptr(len(slab)), uintptr(unsafe.Pointer(&vec[0])))
+	if errno != 0 {
+		return int64(len(slab))
+	}
+	var n int64
+	for _, v := range vec {
+		n += int64(v & 1)
+	}
+	return min(n*int64(page), int64(len(slab)))
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package liteLRU
+
+import "io/fs"
+
+// rootFS returns nil: cgroup memory limits are only read on Linux.
+func rootFS() fs.FS {
+	return nil
+}
+
+// residentBytes returns len(slab): residency is only measured on Linux.
+func residentBytes(slab []byte) int64 {
+	return int64(len(slab))
+}
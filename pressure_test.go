@@ -0,0 +1,256 @@
+package liteLRU
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+	"unsafe"
+)
+
+func TestFootprint(t *testing.T) {
+	c := NewLRUCache(1024, 10)
+	defer c.Close()
+	f := c.Footprint()
+	if want := int64(1024*c.stride + 16*unsafe.Sizeof(chunk{})); f.OffHeap != want {
+		t.Fatalf("off-heap %d, want %d", f.OffHeap, want)
+	}
+	if want := int64(1024*(16+16+24+8) + len(c.stats.mem)); f.Heap != want {
+		t.Fatalf("heap %d, want %d", f.Heap, want)
+	}
+
+	a := NewLRUCache(1024, 10, WithArena(0), WithCompactLayout())
+	defer a.Close()
+	fa := a.Footprint()
+	if fa.OffHeap <= int64(len(a.states)) || fa.OffHeap != int64(len(a.states)+len(a.chunksSlab)+len(a.arena.slab)) {
+		t.Fatalf("arena off-heap %d", fa.OffHeap)
+	}
+	if fa.Heap >= f.Heap/4 {
+		t.Fatalf("arena heap %d vs %d", fa.Heap, f.Heap)
+	}
+}
+
+// pressured returns a cache whose pressure samples report *level percent of
+// a limit, with samples taken only by explicit relieve calls.
+func pressured(ways int, level *atomic.Int64) *LRUCache {
+	return NewLRUCache(4096, 10, WithWays(ways), WithMemoryPressure(MemoryPressure{
+		Interval: time.Hour,
+		Sample:   func() (uint64, uint64, bool) { return uint64(level.Load()), 100, true },
+	}))
+}
+
+func TestMemoryPressureShrinksAndGrows(t *testing.T) {
+	for _, ways := range []int{16, 64, 128} {
+		t.Run(fmt.Sprintf("%d-way", ways), func(t *testing.T) {
+			var level atomic.Int64
+			c := pressured(ways, &level)
+			defer c.Close()
+			fill := func(round int) {
+				for i := 0; i < 8192; i++ {
+					c.Add("GET", fmt.Sprintf("/%d/%d", round, i), nil, []Param{{Key: "i", Value: fmt.Sprint(i)}})
+				}
+			}
+			fill(0)
+
+			level.Store(95)
+			minWays := max(ways/8, 2)
+			for want := ways / 2; want >= minWays; want /= 2 {
+				c.relieve()
+				if got := c.EffectiveCapacity(); got != 4096/ways*want {
+					t.Fatalf("effective capacity %d, want %d", got, 4096/ways*want)
+				}
+				if n := c.Len(); n > c.EffectiveCapacity() {
+					t.Fatalf("%d entries left in %d slots", n, c.EffectiveCapacity())
+				}
+			}
+			c.relieve()
+			if got := c.EffectiveCapacity(); got != 4096/ways*minWays {
+				t.Fatalf("shrank below MinWays: %d", got)
+			}
+
+			// Inserts stay within the shrunken sets, and survivors still hit.
+			fill(1)
+			if n := c.Len(); n > c.EffectiveCapacity() || n < c.EffectiveCapacity()/2 {
+				t.Fatalf("%d entries in %d slots", n, c.EffectiveCapacity())
+			}
+			hits := 0
+			for i := 0; i < 8192; i++ {
+				if _, p, ok := c.Get("GET", fmt.Sprintf("/1/%d", i), nil); ok {
+					if p[0].Value != fmt.Sprint(i) {
+						t.Fatalf("entry %d has params %+v", i, p)
+					}
+					hits++
+				}
+			}
+			if hits == 0 {
+				t.Fatal("no survivors")
+			}
+
+			// In between the thresholds nothing changes; below Low it grows back.
+			level.Store(80)
+			c.relieve()
+			if c.EffectiveCapacity() != 4096/ways*minWays {
+				t.Fatal("capacity changed between the thresholds")
+			}
+			level.Store(10)
+			for i := 0; i < 8; i++ {
+				c.relieve()
+			}
+			if c.EffectiveCapacity() != 4096 {
+				t.Fatalf("grew back to %d", c.EffectiveCapacity())
+			}
+			fill(2)
+			if n := c.Len(); n < 3000 {
+				t.Fatalf("only %d entries after growing back", n)
+			}
+		})
+	}
+}
+
+func TestMemoryPressureWatcher(t *testing.T) {
+	var samples atomic.Int64
+	c := NewLRUCache(1024, 10, WithMemoryPressure(MemoryPressure{
+		Interval: time.Millisecond,
+		Sample: func() (uint64, uint64, bool) {
+			samples.Add(1)
+			return 99, 100, true
+		},
+	}))
+	deadline := time.Now().Add(5 * time.Second)
+	for c.EffectiveCapacity() == 1024 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c.EffectiveCapacity() == 1024 {
+		t.Fatal("watcher never shrank the cache")
+	}
+	c.Close()
+	n := samples.Load()
+	time.Sleep(20 * time.Millisecond)
+	if samples.Load() != n {
+		t.Fatal("watcher still running after Close")
+	}
+}
+
+func TestCgroupMemory(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	for _, tc := range []struct {
+		name        string
+		fsys        fstest.MapFS
+		used, limit uint64
+		ok          bool
+	}{
+		{"leaf", fstest.MapFS{
+			"proc/self/cgroup":                     file("0::/pod/app\n"),
+			"sys/fs/cgroup/pod/app/memory.max":     file("1000\n"),
+			"sys/fs/cgroup/pod/app/memory.current": file("600\n"),
+		}, 600, 1000, true},
+		// An unlimited leaf inherits the limit of its parent.
+		{"ancestor", fstest.MapFS{
+			"proc/self/cgroup":                     file("0::/pod/app\n"),
+			"sys/fs/cgroup/pod/app/memory.max":     file("max\n"),
+			"sys/fs/cgroup/pod/app/memory.current": file("600\n"),
+			"sys/fs/cgroup/pod/memory.max":         file("2000\n"),
+			"sys/fs/cgroup/pod/memory.current":     file("900\n"),
+		}, 900, 2000, true},
+		{"namespaced", fstest.MapFS{
+			"proc/self/cgroup":             file("0::/\n"),
+			"sys/fs/cgroup/memory.max":     file("4096\n"),
+			"sys/fs/cgroup/memory.current": file("1024\n"),
+		}, 1024, 4096, true},
+		{"unlimited", fstest.MapFS{
+			"proc/self/cgroup":                 file("0::/app\n"),
+			"sys/fs/cgroup/app/memory.max":     file("max\n"),
+			"sys/fs/cgroup/app/memory.current": file("600\n"),
+		}, 0, 0, false},
+		{"v1", fstest.MapFS{
+			"proc/self/cgroup": file("4:memory:/app\n"),
+		}, 0, 0, false},
+		{"escape", fstest.MapFS{
+			"proc/self/cgroup": file("0::/../../..\n"),
+		}, 0, 0, false},
+	} {
+		used, limit, ok := cgroupMemory(tc.fsys)
+		if used != tc.used || limit != tc.limit || ok != tc.ok {
+			t.Errorf("%s: %d/%d %v, want %d/%d %v", tc.name, used, limit, ok, tc.used, tc.limit, tc.ok)
+		}
+	}
+	if _, _, ok := cgroupMemory(nil); ok {
+		t.Error("nil fs reported a limit")
+	}
+}
+
+func TestRuntimeMemoryCountsOffHeap(t *testing.T) {
+	c := NewLRUCache(1<<16, 10, WithArena(1<<30))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Add("GET", fmt.Sprintf("/%d", i), nil, nil)
+	}
+
+	old := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(old)
+	debug.SetMemoryLimit(1 << 40)
+	used, limit, ok := c.runtimeMemory()
+	f := c.Footprint()
+	if !ok || limit != 1<<40 || used <= uint64(f.Resident) {
+		t.Fatalf("runtime memory %d/%d %v, resident off-heap %d", used, limit, ok, f.Resident)
+	}
+	// The arena is mapped but barely touched, so it must not count in full.
+	if runtime.GOOS == "linux" && (f.Resident <= 0 || used >= uint64(f.OffHeap)) {
+		t.Fatalf("runtime memory %d, off-heap %d of which %d resident", used, f.OffHeap, f.Resident)
+	}
+}
+
+func TestMemoryPressureTrimsOnlyAfterShrinking(t *testing.T) {
+	var level atomic.Int64
+	c := pressured(64, &level)
+	defer c.Close()
+
+	level.Store(95)
+	for i := 0; i < 8; i++ {
+		c.relieve()
+	}
+	if c.EffectiveCapacity() != 4096/64*8 {
+		t.Fatalf("effective capacity %d", c.EffectiveCapacity())
+	}
+	// At MinWays further samples above High have nothing left to do.
+	if c.watch.trims != 0 {
+		t.Fatalf("%d trims pending at MinWays", c.watch.trims)
+	}
+}
+
+func TestMemoryPressureConcurrent(t *testing.T) {
+	// Arena mode: heap-mode params recycling is not race-free under
+	// concurrent rewrites of one entry, which this test does not target.
+	var level atomic.Int64
+	level.Store(95)
+	c := NewLRUCache(4096, 10, WithArena(0), WithMemoryPressure(MemoryPressure{
+		Interval: time.Millisecond,
+		Sample:   func() (uint64, uint64, bool) { return uint64(level.Load()), 100, true },
+	}))
+	defer c.Close()
+
+	done := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		go func(w int) {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 20000; i++ {
+				path := fmt.Sprintf("/r/%d", (i*7+w)%6000)
+				if i%3 == 0 {
+					c.Add("GET", path, nil, []Param{{Key: "path", Value: path}})
+				} else if _, p, ok := c.Get("GET", path, nil); ok && p[0].Value != path {
+					t.Errorf("corrupt entry for %s: %+v", path, p)
+					return
+				}
+				if i == 10000 {
+					level.Store(10)
+				}
+			}
+		}(w)
+	}
+	for w := 0; w < 4; w++ {
+		<-done
+	}
+}
@@ -0,0 +1,215 @@
+package liteLRU
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// HandlerRegistry resolves the handler names stored in a shared cache to the
+// handlers of the calling process. It is called on every hit, with a name
+// that aliases shared memory and must not be retained, and returns nil for
+// unknown names.
+type HandlerRegistry func(name string) HandlerFunc
+
+// ErrSharedLayout is returned by OpenShared when the file holds a cache with
+// a different version or geometry than requested, or something else entirely.
+var ErrSharedLayout = errors.New("liteLRU: shared cache file has a different layout")
+
+// errSharedOption is returned by OpenShared for options that keep state on
+// the Go heap of one process, or that would let one process shrink the cache
+// of all.
+var errSharedOption = errors.New("liteLRU: WithValues, WithVictimBuffer, WithLoader and WithMemoryPressure are not supported by shared caches")
+
+const (
+	sharedMagic   = "liteLRU\x00"
+	sharedVersion = 2
+	sharedPage    = 4096 // alignment of the regions within the file
+)
+
+// Layout flags recorded in the header; every process must agree on them.
+const (
+	sharedSig16 = 1 << iota
+	sharedFNV
+	sharedTwoChoice
+)
+
+// sharedHeader is the first page of a shared cache file. Everything but gen
+// is written once, by the process that creates the file, before it lets any
+// other process attach. The file is machine-local and in native byte order.
+type sharedHeader struct {
+	magic    [8]byte
+	version  uint32
+	flags    uint32
+	capacity uint32
+	ways     uint32
+	stride   uint32
+	_        uint32
+	granules uint64        // arena size
+	created  int64         // wall-clock base of deadlines, Unix nanoseconds
+	gen      atomic.Uint32 // the cache generation, shared by every process
+}
+
+// sharedLayout is the geometry a process expects of a shared cache file.
+type sharedLayout struct {
+	capacity uint32
+	ways     uint32
+	stride   uint32
+	granules uint64
+	flags    uint32
+}
+
+// offsets returns where the seqlocks, chunks and arena start in the file,
+// and its total size.
+func (l sharedLayout) offsets() (states, chunks, arena, total int) {
+	page := func(n int) int { return (n + sharedPage - 1) &^ (sharedPage - 1) }
+	states = sharedPage
+	chunks = states + page(int(l.capacity)*int(l.stride))
+	arena = chunks + page(int(l.capacity)/64*int(unsafe.Sizeof(chunk{})))
+	_, size := arenaSize(int(l.granules) * arenaGranule)
+	return states, chunks, arena, arena + size
+}
+
+// matches reports whether h describes a cache of layout l.
+func (l sharedLayout) matches(h *sharedHeader) error {
+	switch {
+	case string(h.magic[:]) != sharedMagic:
+		return fmt.Errorf("%w: not a liteLRU cache file", ErrSharedLayout)
+	case h.version != sharedVersion:
+		return fmt.Errorf("%w: version %d, want %d", ErrSharedLayout, h.version, sharedVersion)
+	case h.capacity != l.capacity || h.ways != l.ways || h.stride != l.stride ||
+		h.granules != l.granules || h.flags != l.flags:
+		return fmt.Errorf("%w: capacity %d, %d ways, stride %d, %d arena granules, flags %#x; want %d, %d, %d, %d, %#x",
+			ErrSharedLayout, h.capacity, h.ways, h.stride, h.granules, h.flags,
+			l.capacity, l.ways, l.stride, l.granules, l.flags)
+	}
+	return nil
+}
+
+// sharedFlags returns the layout flags implied by the options.
+func (cfg *config) sharedFlags() uint32 {
+	var f uint32
+	if cfg.sig16 {
+		f |= sharedSig16
+	}
+	if cfg.fnv {
+		f |= sharedFNV
+	}
+	if cfg.twoChoice {
+		f |= sharedTwoChoice
+	}
+	return f
+}
+
+// sharedConfig holds the settings OpenShared adds to the options.
+type sharedConfig struct {
+	path string
+}
+
+// OpenShared opens the cache in the file at path, creating it if needed, so
+// that several processes on the same machine can share one cache and its
+// entries survive restarts. A file under /dev/shm keeps it in memory. The
+// seqlocks, chunks and arena all live in the file mapping, laid out behind a
+// versioned header; the options that affect placement (capacity, WithWays,
+// WithCompactLayout, WithCacheLineSize, WithArena, With16BitSignatures,
+// WithFNVHash, WithTwoChoice) must be the same in every process, otherwise
+// OpenShared fails with ErrSharedLayout.
+//
+// Handlers cannot cross process boundaries, so a shared cache stores handler
+// names instead: AddNamed records one, and every process resolves it through
+// its own registry on each hit. Entries added with Add have no handler.
+//
+// Statistics, namespaces and View pins are per process: namespaces must be
+// created in the same order everywhere to get the same ids, and their quotas
+// only count the process's own inserts. WithValues, WithVictimBuffer and
+// WithLoader keep heap state, and WithMemoryPressure would shrink the cache
+// of every process from one, so they are rejected. As in arena mode, Get
+// copies params out of the mapping. View lends them in place, but its pin
+// only holds off this process's writers, so another process may rewrite them
+// while fn runs.
+//
+// A process that dies in the middle of a write leaves that slot locked.
+// Whoever next opens the file while no other process has it open resets such
+// slots.
+func OpenShared(path string, capacity, maxParams int, registry HandlerRegistry, opts ...Option) (*LRUCache, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.values || cfg.victims != 0 || cfg.load != nil || cfg.pressure != nil {
+		return nil, errSharedOption
+	}
+	cfg.arena = true
+	cfg.shared = &sharedConfig{path: path}
+	c, err := newLRUCache(capacity, maxParams, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.registry = registry
+	if c.shared.sole {
+		c.recoverSlots()
+	}
+	if err := c.shared.attach(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// AddNamed adds or updates an entry whose handler is known by name. In a
+// shared cache each process resolves the name through its HandlerRegistry;
+// in a cache without a registry the entry has no handler.
+func (c *LRUCache) AddNamed(method, path, handler string, params []Param) {
+	c.add(method, path, nil, params, &entryMeta{name: handler})
+}
+
+// handler returns the handler of slot idx. Shared caches resolve the handler
+// name in the slot's record. Callers validate the answer with the seqlock.
+func (c *LRUCache) handler(idx uint32) HandlerFunc {
+	if c.handlers != nil {
+		return c.handlers[idx].Load()
+	}
+	if c.registry == nil {
+		return nil
+	}
+	b := c.record(idx)
+	if b == nil {
+		return nil
+	}
+	if name, ok := recordName(b); ok && name != "" {
+		return c.registry(name)
+	}
+	return nil
+}
+
+// setHandler stores the handler of slot idx. Shared caches keep none.
+func (c *LRUCache) setHandler(idx uint32, h HandlerFunc) {
+	if c.handlers != nil {
+		c.handlers[idx].Store(h)
+	}
+}
+
+// recoverSlots releases the slots a crashed process left mid-write: writing
+// bits it held and odd seqlocks. It runs only while no other process has the
+// file open. The arena block a half-finished write had already allocated is
+// leaked; the one the slot still references is freed.
+func (c *LRUCache) recoverSlots() {
+	for group := uint32(0); group < c.numGroups; group++ {
+		chk := &c.chunks[group]
+		chk.writing.Store(0)
+		for bit := uint32(0); bit < 64; bit++ {
+			st := c.state(group*64 + bit)
+			seq := st.seq.Load()
+			if seq%2 == 0 {
+				continue
+			}
+			chk.valid.And(^(uint64(1) << bit))
+			chk.accessed.And(^(uint64(1) << bit))
+			if ref := st.ref.Swap(0); ref != 0 {
+				c.arena.free(ref)
+			}
+			st.seq.Store(seq + 1)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//go:build linux
+
+package liteLRU
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sharedMapping is a shared cache file mapped into this process. Attached
+// processes hold a shared flock on the file; the process that creates or
+// recovers it holds an exclusive one until attach.
+type sharedMapping struct {
+	file   *os.File
+	mem    []byte
+	hdr    *sharedHeader
+	states []byte
+	chunks []chunk
+	arena  *keyArena
+	sole   bool // no other process had the file open
+}
+
+// mapShared opens, and if needed creates, the shared cache file at path and
+// maps it. A process that finds the file unused takes an exclusive lock and
+// (re)initialises it unless it already holds a valid cache; otherwise it
+// waits for a shared lock, which excludes an initialiser in progress.
+func mapShared(path string, l sharedLayout) (*sharedMapping, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	m := &sharedMapping{file: f}
+	if err := m.open(l); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *sharedMapping) open(l sharedLayout) error {
+	fd := int(m.file.Fd())
+	err := unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB)
+	switch {
+	case err == nil:
+		m.sole = true
+	case errors.Is(err, unix.EWOULDBLOCK):
+		if err := unix.Flock(fd, unix.LOCK_SH); err != nil {
+			return os.NewSyscallError("flock", err)
+		}
+	default:
+		return os.NewSyscallError("flock", err)
+	}
+
+	statesOff, chunksOff, arenaOff, total := l.offsets()
+	fi, err := m.file.Stat()
+	if err != nil {
+		return err
+	}
+	fresh := fi.Size() == 0
+	if m.sole && !fresh && fi.Size() >= sharedPage {
+		// A file whose creator died before writing the magic is recreated.
+		var magic [8]byte
+		if _, err := m.file.ReadAt(magic[:], 0); err != nil {
+			return err
+		}
+		fresh = magic == [8]byte{}
+	}
+	if fresh {
+		if !m.sole {
+			return fmt.Errorf("%w: file is empty", ErrSharedLayout)
+		}
+		// Truncating to zero first zeroes whatever a failed creator wrote.
+		if err := m.file.Truncate(0); err != nil {
+			return err
+		}
+		if err := m.file.Truncate(int64(total)); err != nil {
+			return err
+		}
+	} else if fi.Size() != int64(total) {
+		return fmt.Errorf("%w: file is %d bytes, want %d", ErrSharedLayout, fi.Size(), total)
+	}
+
+	mem, err := unix.Mmap(fd, 0, total, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	m.mem = mem
+	m.hdr = (*sharedHeader)(unsafe.Pointer(&mem[0]))
+	if fresh {
+		m.hdr.version = sharedVersion
+		m.hdr.flags = l.flags
+		m.hdr.capacity = l.capacity
+		m.hdr.ways = l.ways
+		m.hdr.stride = l.stride
+		m.hdr.granules = l.granules
+		m.hdr.created = time.Now().UnixNano()
+		copy(m.hdr.magic[:], sharedMagic)
+	}
+	if err := l.matches(m.hdr); err != nil {
+		unix.Munmap(mem)
+		return err
+	}
+
+	m.states = mem[statesOff:chunksOff]
+	m.chunks = unsafe.Slice((*chunk)(unsafe.Pointer(&mem[chunksOff])), l.capacity/64)
+	m.arena = arenaOver(mem[arenaOff:], int(l.granules))
+	return nil
+}
+
+// attach downgrades an exclusive lock taken by open to a shared one, letting
+// other processes in.
+func (m *sharedMapping) attach() error {
+	if !m.sole {
+		return nil
+	}
+	return os.NewSyscallError("flock", unix.Flock(int(m.file.Fd()), unix.LOCK_SH))
+}
+
+// close unmaps the file and drops the lock.
+func (m *sharedMapping) close() {
+	unix.Munmap(m.mem)
+	m.file.Close()
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package liteLRU
+
+import "errors"
+
+// sharedMapping is a shared cache file mapped into this process; shared
+// caches are only implemented on Linux.
+type sharedMapping struct {
+	mem    []byte
+	hdr    *sharedHeader
+	states []byte
+	chunks []chunk
+	arena  *keyArena
+	sole   bool
+}
+
+func mapShared(string, sharedLayout) (*sharedMapping, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (m *sharedMapping) attach() error { return nil }
+
+func (m *sharedMapping) close() {}
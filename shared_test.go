@@ -0,0 +1,275 @@
+//go:build linux
+
+package liteLRU
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// registryOf returns a registry whose handlers record their name, prefixed
+// with who, in *calls.
+func registryOf(who string, calls *[]string) HandlerRegistry {
+	return func(name string) HandlerFunc {
+		if name == "missing" {
+			return nil
+		}
+		name = who + ":" + name
+		return func() { *calls = append(*calls, name) }
+	}
+}
+
+func TestSharedAttach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	var calls []string
+	a, err := OpenShared(path, 1024, 10, registryOf("a", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := OpenShared(path, 1024, 10, registryOf("b", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	a.AddNamed("GET", "/users/42", "users", []Param{{Key: "id", Value: "42"}})
+	h, params, ok := b.Get("GET", "/users/42", nil)
+	if !ok || len(params) != 1 || params[0].Value != "42" {
+		t.Fatalf("b.Get = %v %+v", ok, params)
+	}
+	h()
+	if len(calls) != 1 || calls[0] != "b:users" {
+		t.Fatalf("handler resolved as %v", calls)
+	}
+
+	// Entries without a resolvable handler still hit.
+	b.AddNamed("GET", "/gone", "missing", nil)
+	b.Add("GET", "/plain", func() {}, nil)
+	for _, p := range []string{"/gone", "/plain"} {
+		if h, _, ok := a.Get("GET", p, nil); !ok || h != nil {
+			t.Fatalf("%s: %v, handler %v", p, ok, h != nil)
+		}
+	}
+	if a.Len() != 3 || b.Len() != 3 {
+		t.Fatalf("Len = %d, %d", a.Len(), b.Len())
+	}
+
+	b.Remove("GET", "/plain")
+	if _, _, ok := a.Get("GET", "/plain", nil); ok {
+		t.Fatal("removal not visible")
+	}
+	a.InvalidateAll()
+	if _, _, ok := b.Get("GET", "/users/42", nil); ok {
+		t.Fatal("invalidation not visible")
+	}
+}
+
+func TestSharedSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	c, err := OpenShared(path, 1024, 10, nil, WithWays(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		c.AddNamed("GET", fmt.Sprintf("/r/%d", i), "h", []Param{{Key: "i", Value: fmt.Sprint(i)}})
+	}
+	c.AddNotFound("GET", "/missing", 404)
+	n := c.Len()
+	c.Close()
+
+	c, err = OpenShared(path, 1024, 10, nil, WithWays(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Len() != n {
+		t.Fatalf("Len = %d after reopening, was %d", c.Len(), n)
+	}
+	for i := 0; i < 500; i++ {
+		if _, p, ok := c.Get("GET", fmt.Sprintf("/r/%d", i), nil); ok && p[0].Value != fmt.Sprint(i) {
+			t.Fatalf("entry %d has params %+v", i, p)
+		}
+	}
+	if _, _, status, res := c.Lookup("GET", "/missing", nil); res != NegativeHit || status != 404 {
+		t.Fatalf("negative entry: %v %d", res, status)
+	}
+}
+
+func TestSharedLayoutMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	c, err := OpenShared(path, 1024, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range [][]Option{{}, {WithTwoChoice()}, {With16BitSignatures()}, {WithArena(1 << 20)}, {WithCompactLayout()}} {
+		capacity := 1024
+		if len(opts) == 0 {
+			capacity = 2048
+		}
+		if d, err := OpenShared(path, capacity, 10, nil, opts...); !errors.Is(err, ErrSharedLayout) {
+			t.Errorf("options %d: err = %v", len(opts), err)
+			if d != nil {
+				d.Close()
+			}
+		}
+	}
+	c.Close()
+
+	// A newer version is refused, and so is a file that is not a cache.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[8]++
+	os.WriteFile(path, b, 0o600)
+	if _, err := OpenShared(path, 1024, 10, nil); !errors.Is(err, ErrSharedLayout) {
+		t.Fatalf("version bump: err = %v", err)
+	}
+	os.WriteFile(path, []byte("#!/bin/sh\necho not a cache\n"), 0o600)
+	if _, err := OpenShared(path, 1024, 10, nil); !errors.Is(err, ErrSharedLayout) {
+		t.Fatalf("foreign file: err = %v", err)
+	}
+}
+
+func TestSharedRejectsHeapOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	load := func(method, path string) (HandlerFunc, []Param, error) { return nil, nil, nil }
+	for _, opt := range []Option{WithValues(), WithVictimBuffer(0), WithLoader(load, 0, 0)} {
+		if c, err := OpenShared(path, 1024, 10, nil, opt); err == nil {
+			c.Close()
+			t.Fatal("heap-only option accepted")
+		}
+	}
+}
+
+// crashWrite leaves the slot of path as a writer that died mid-write would:
+// writing bit held and seqlock odd.
+func crashWrite(t *testing.T, c *LRUCache, path string) (chk *chunk, idx, bit uint32) {
+	group, _, sig, psig := c.locate("GET", path, nil, 0)
+	chk, idx, bit, _, ok := c.probe(group, sig, psig, "GET", path, nil, 0)
+	if !ok || !claimSlot(chk, bit) {
+		t.Fatalf("cannot claim %s", path)
+	}
+	c.state(idx).seq.Add(1)
+	return chk, idx, bit
+}
+
+func TestSharedRecoversCrashedWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	c, err := OpenShared(path, 1024, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AddNamed("GET", "/a", "h", nil)
+	c.AddNamed("GET", "/b", "h", nil)
+	crashWrite(t, c, "/a")
+
+	// A process attaching alongside a live one must not touch its writes.
+	d, err := OpenShared(path, 1024, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, idx, _ := crashWrite(t, d, "/b")
+	d.Close()
+	if c.state(idx).seq.Load()%2 == 0 {
+		t.Fatal("second process reset a live write")
+	}
+	c.Close()
+
+	c, err = OpenShared(path, 1024, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for g := range c.chunks {
+		if w := c.chunks[g].writing.Load(); w != 0 {
+			t.Fatalf("chunk %d still has writing bits %b", g, w)
+		}
+	}
+	if c.state(idx).seq.Load()%2 != 0 || c.Len() != 0 {
+		t.Fatalf("seq %d, Len %d after recovery", c.state(idx).seq.Load(), c.Len())
+	}
+	c.AddNamed("GET", "/a", "h", nil)
+	if _, _, ok := c.Get("GET", "/a", nil); !ok {
+		t.Fatal("recovered slot not reusable")
+	}
+}
+
+// TestSharedAcrossProcesses fills a shared cache from a child process and
+// reads it back in this one.
+func TestSharedAcrossProcesses(t *testing.T) {
+	if path := os.Getenv("LITELRU_SHARED_CHILD"); path != "" {
+		c, err := OpenShared(path, 4096, 10, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for i := 0; i < 1000; i++ {
+			c.AddNamed("GET", fmt.Sprintf("/child/%d", i), "child", []Param{{Key: "i", Value: fmt.Sprint(i)}})
+		}
+		c.Close()
+		os.Exit(0)
+	}
+
+	path := filepath.Join(t.TempDir(), "cache")
+	var calls []string
+	c, err := OpenShared(path, 4096, 10, registryOf("parent", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSharedAcrossProcesses$")
+	cmd.Env = append(os.Environ(), "LITELRU_SHARED_CHILD="+path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child: %v\n%s", err, out)
+	}
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		if h, p, ok := c.Get("GET", fmt.Sprintf("/child/%d", i), nil); ok {
+			if p[0].Value != fmt.Sprint(i) {
+				t.Fatalf("entry %d has params %+v", i, p)
+			}
+			h()
+			hits++
+		}
+	}
+	if hits < 900 || calls[0] != "parent:child" {
+		t.Fatalf("%d hits, calls %v", hits, calls[:min(len(calls), 3)])
+	}
+}
+
+func TestSharedPrefaultKeepsLiveData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	var calls []string
+	a, err := OpenShared(path, 1024, 10, registryOf("a", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	for i := 0; i < 200; i++ {
+		a.AddNamed("GET", fmt.Sprintf("/p/%d", i), "h", []Param{{Key: "i", Value: fmt.Sprint(i)}})
+	}
+
+	// Prefaulting the mapping of an attaching process must not write to it.
+	b, err := OpenShared(path, 1024, 10, registryOf("b", &calls), WithPrefault())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	t.Logf("prefault: %v", b.MmapStatus().Prefault)
+	for i := 0; i < 200; i++ {
+		_, params, ok := b.Get("GET", fmt.Sprintf("/p/%d", i), nil)
+		if !ok || params[0].Value != fmt.Sprint(i) {
+			t.Fatalf("/p/%d lost after a prefaulting attach: %v %+v", i, ok, params)
+		}
+	}
+	if a.Len() != 200 {
+		t.Fatalf("Len = %d", a.Len())
+	}
+}
@@ -0,0 +1,47 @@
+package liteLRU
+
+import "sync/atomic"
+
+// With16BitSignatures widens the per-slot route signature from 8 to 16 bits
+// by keeping a second signature byte per slot in its own SWAR plane. A lookup
+// then only visits slots whose two bytes both match, which cuts the expected
+// number of false-positive candidates per probe of a full 64-way set from
+// about 1/4 to about 1/1000, each of which would otherwise cost seqlock loads
+// and key comparisons. Adds write one more signature word. FalseProbes
+// measures the difference.
+func With16BitSignatures() Option {
+	return func(cfg *config) {
+		cfg.sig16 = true
+	}
+}
+
+// matchBytes returns a word with the high bit of every byte of word that
+// equals b set. Unlike hasByteSWAR it is exact: a match never marks a
+// neighbouring byte through borrow propagation.
+func matchBytes(word uint64, b uint8) uint64 {
+	v := word ^ uint64(b)*0x0101010101010101
+	return ^((v&0x7F7F7F7F7F7F7F7F + 0x7F7F7F7F7F7F7F7F) | v) & 0x8080808080808080
+}
+
+// matchSigsSWAR is the portable matchSigs: it compares the signature words
+// of a plane against sig eight bytes at a time in general-purpose registers.
+func matchSigsSWAR(plane *[8]atomic.Uint64, sig uint8) uint64 {
+	var m uint64
+	for i := uint32(0); i < 8; i++ {
+		// Gather the high bit of each matching byte into one byte.
+		hit := matchBytes(plane[i].Load(), sig) >> 7 * 0x0102040810204080 >> 56
+		m |= hit << (i * 8)
+	}
+	return m
+}
+
+// FalseProbes returns how many lookup candidates matched a route signature
+// but turned out to hold a different key. Entries invalidated by InvalidateAll
+// and entries of other namespaces are skipped without being counted.
+func (c *LRUCache) FalseProbes() int64 {
+	var n int64
+	for i := uint64(0); i < 64; i++ {
+		n += c.stats.at(i).falseProbes.Load()
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package liteLRU
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+// TestMatchSigs checks the architecture-specific scan and the portable SWAR
+// scan against a byte-by-byte reference.
+func TestMatchSigs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var plane [8]atomic.Uint64
+	for round := 0; round < 5000; round++ {
+		var raw [64]byte
+		for i := range raw {
+			// A small alphabet makes adjacent equal and off-by-one bytes
+			// common; every few rounds use the full byte range instead.
+			if round%4 == 0 {
+				raw[i] = byte(r.Intn(256))
+			} else {
+				raw[i] = byte(r.Intn(4))
+			}
+		}
+		for i := 0; i < 8; i++ {
+			var w uint64
+			for j := 0; j < 8; j++ {
+				w |= uint64(raw[i*8+j]) << (j * 8)
+			}
+			plane[i].Store(w)
+		}
+		sig := raw[r.Intn(64)]
+		if round%3 == 0 {
+			sig = byte(r.Intn(256))
+		}
+		var want uint64
+		for i, b := range raw {
+			if b == sig {
+				want |= 1 << i
+			}
+		}
+		if got := matchSigs(&plane, sig); got != want {
+			t.Fatalf("matchSigs(%v, %d) = %064b, want %064b", raw, sig, got, want)
+		}
+		if got := matchSigsSWAR(&plane, sig); got != want {
+			t.Fatalf("matchSigsSWAR(%v, %d) = %064b, want %064b", raw, sig, got, want)
+		}
+	}
+}
+
+func BenchmarkMatchSigs(b *testing.B) {
+	var plane [8]atomic.Uint64
+	for i := range plane {
+		plane[i].Store(0x0102030405060708 * uint64(i+1))
+	}
+	var sink uint64
+	b.Run("arch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sink += matchSigs(&plane, uint8(i))
+		}
+	})
+	b.Run("swar", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sink += matchSigsSWAR(&plane, uint8(i))
+		}
+	})
+	_ = sink
+}
+
+func TestFalseProbes(t *testing.T) {
+	probes := func(opts ...Option) int64 {
+		c := NewLRUCache(1024, 10, opts...)
+		defer c.Close()
+		paths := collidingPaths(c, 64)
+		for _, p := range paths {
+			c.Add("GET", p, nil, nil)
+		}
+		for _, p := range paths {
+			if _, _, ok := c.Get("GET", p, nil); !ok {
+				t.Fatalf("%s missed", p)
+			}
+		}
+		// Unknown methods of cached paths miss in the same full set, where
+		// every candidate is a false one.
+		for i := 0; i < 20000; i++ {
+			c.Get(fmt.Sprint("M", i), paths[i%len(paths)], nil)
+		}
+		return c.FalseProbes()
+	}
+
+	narrow, wide := probes(), probes(With16BitSignatures())
+	if narrow < 1000 {
+		t.Fatalf("8-bit signatures: only %d false probes, expected about 5000", narrow)
+	}
+	if wide*20 > narrow {
+		t.Fatalf("16-bit signatures: %d false probes vs %d with 8 bits", wide, narrow)
+	}
+}
+
+func TestFalseProbesIgnoreStaleEntries(t *testing.T) {
+	c := NewLRUCache(1024, 10)
+	defer c.Close()
+	paths := collidingPaths(c, 64)
+	for _, p := range paths {
+		c.Add("GET", p, nil, nil)
+	}
+	c.InvalidateAll()
+	before := c.FalseProbes()
+	for _, p := range paths {
+		if _, _, ok := c.Get("GET", p, nil); ok {
+			t.Fatalf("%s survived InvalidateAll", p)
+		}
+	}
+	if n := c.FalseProbes() - before; n != 0 {
+		t.Fatalf("invalidated entries counted as %d false probes", n)
+	}
+}
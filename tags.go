@@ -0,0 +1,90 @@
+package liteLRU
+
+import (
+	"math/bits"
+	"runtime"
+)
+
+// MaxTags is the number of tags a single entry can carry.
+const MaxTags = 4
+
+// hashTag folds a tag into the 32-bit reference stored per slot. Distinct tags
+// that collide only cause extra entries to be invalidated, never fewer.
+func hashTag(tag string) uint32 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(tag); i++ {
+		hash ^= uint64(tag[i])
+		hash *= 1099511628211
+	}
+	h := uint32(hash ^ hash>>32)
+	if h == 0 {
+		h = 1
+	}
+	return h
+}
+
+// AddTagged adds or updates an entry like Add and attaches up to MaxTags tags
+// to it, so that related entries which do not share a path prefix can later be
+// evicted together with InvalidateTag. Tags are stored as 32-bit hashes in the
+// slot's off-heap state line. Entries with more than MaxTags tags are not
+// cached and count as drops, since silently ignoring a tag would let them
+// survive an invalidation.
+func (c *LRUCache) AddTagged(method, path string, handler HandlerFunc, params []Param, tags ...string) {
+	if len(tags) > MaxTags {
+		_, stripeIdx, _, _ := c.locate(method, path, nil, 0)
+		c.drop(stripeIdx, nil)
+		return
+	}
+	var meta entryMeta
+	for i, tag := range tags {
+		meta.tags[i] = hashTag(tag)
+	}
+	c.add(method, path, handler, params, &meta)
+}
+
+// InvalidateTag evicts every entry carrying tag and returns how many were
+// removed. It compares the per-slot tag hashes of valid slots only, never key
+// strings. A matching slot that is being written concurrently is claimed once
+// its writer is done and evicted if it still carries the tag, so a rewrite
+// that keeps the tags, such as a loader refresh, cannot let the entry survive.
+// Matching entries parked in the victim buffer are discarded too but not
+// counted.
+func (c *LRUCache) InvalidateTag(tag string) int {
+	h := hashTag(tag)
+	removed := 0
+	for group := uint32(0); group < c.numGroups; group++ {
+		chk := &c.chunks[group]
+		for m := chk.valid.Load(); m != 0; m &= m - 1 {
+			bit := uint32(bits.TrailingZeros64(m))
+			idx := group*64 + bit
+			for c.hasTag(idx, h) {
+				if !claimSlot(chk, bit) {
+					runtime.Gosched()
+					continue
+				}
+				// Re-check under the writing bit: the slot may have been reused.
+				if chk.valid.Load()&(1<<bit) != 0 && c.hasTag(idx, h) {
+					c.clearSlot(chk, idx, bit)
+					removed++
+				}
+				releaseSlot(chk, bit)
+				break
+			}
+		}
+	}
+	if c.victims != nil {
+		c.victims.invalidateTag(h)
+	}
+	return removed
+}
+
+// hasTag reports whether slot idx carries the tag hash h.
+func (c *LRUCache) hasTag(idx, h uint32) bool {
+	st := c.state(idx)
+	for i := range st.tags {
+		if st.tags[i].Load() == h {
+			return true
+		}
+	}
+	return false
+}
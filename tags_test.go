@@ -0,0 +1,94 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithArena(0)}} {
+		c := NewLRUCache(256, 10, opts...)
+
+		c.AddTagged("GET", "/users/42", nil, nil, "user:42")
+		c.AddTagged("GET", "/users/42/profile", nil, nil, "user:42", "profiles")
+		c.AddTagged("GET", "/avatars/42.png", nil, nil, "user:42")
+		c.AddTagged("GET", "/users/43", nil, nil, "user:43")
+		c.Add("GET", "/health", nil, nil)
+
+		if n := c.InvalidateTag("user:42"); n != 3 {
+			t.Fatalf("expected 3 evictions, got %d", n)
+		}
+		for _, p := range []string{"/users/42", "/users/42/profile", "/avatars/42.png"} {
+			if _, _, ok := c.Get("GET", p, nil); ok {
+				t.Fatalf("%s survived InvalidateTag", p)
+			}
+		}
+		for _, p := range []string{"/users/43", "/health"} {
+			if _, _, ok := c.Get("GET", p, nil); !ok {
+				t.Fatalf("%s wrongly evicted", p)
+			}
+		}
+		if n := c.InvalidateTag("profiles"); n != 0 {
+			t.Fatalf("already evicted entry counted again: %d", n)
+		}
+		c.Close()
+	}
+}
+
+func TestAddUntaggedClearsTags(t *testing.T) {
+	c := NewLRUCache(64, 10)
+	defer c.Close()
+
+	c.AddTagged("GET", "/x", nil, nil, "t")
+	c.Add("GET", "/x", nil, nil)
+	if n := c.InvalidateTag("t"); n != 0 {
+		t.Fatalf("overwritten entry kept its tag: %d", n)
+	}
+}
+
+func TestAddTaggedTooManyTags(t *testing.T) {
+	c := NewLRUCache(64, 10)
+	defer c.Close()
+
+	tags := make([]string, MaxTags+1)
+	for i := range tags {
+		tags[i] = fmt.Sprint("t", i)
+	}
+	c.AddTagged("GET", "/x", nil, nil, tags...)
+	if _, _, ok := c.Get("GET", "/x", nil); ok {
+		t.Fatal("entry with too many tags was cached")
+	}
+	if _, _, drops, _ := c.Stats(); drops != 1 {
+		t.Fatalf("expected a drop, got %d", drops)
+	}
+}
+
+func TestInvalidateTagWaitsForWriters(t *testing.T) {
+	c := NewLRUCache(64, 10)
+	defer c.Close()
+
+	c.AddTagged("GET", "/held", nil, nil, "t")
+	group, _, sig, psig := c.locate("GET", "/held", nil, 0)
+	chk, _, bit, _, ok := c.probe(group, sig, psig, "GET", "/held", nil, 0)
+	if !ok || !claimSlot(chk, bit) {
+		t.Fatal("could not claim the tagged slot")
+	}
+
+	// A writer holding the slot while keeping its tags must not make the
+	// invalidation skip it.
+	done := make(chan int)
+	go func() { done <- c.InvalidateTag("t") }()
+	select {
+	case n := <-done:
+		t.Fatalf("InvalidateTag returned %d while the slot was held", n)
+	case <-time.After(20 * time.Millisecond):
+	}
+	releaseSlot(chk, bit)
+	if n := <-done; n != 1 {
+		t.Fatalf("expected 1 eviction, got %d", n)
+	}
+	if _, _, ok := c.Get("GET", "/held", nil); ok {
+		t.Fatal("tagged entry survived InvalidateTag")
+	}
+}
@@ -0,0 +1,86 @@
+package liteLRU
+
+import "math/bits"
+
+// WithTwoChoice lets every key live in one of two candidate sets instead of
+// one. Add places a new entry in the candidate with more free slots or, when
+// both are full, the one that still has a victim whose CLOCK reference bit is
+// clear; lookups probe the home set first and the alternate set only on a
+// miss there. This cuts conflict misses when hot keys cluster in a few sets,
+// at the cost of a second SWAR scan for misses and keys placed in their
+// alternate set.
+//
+// The alternate set depends only on the path, so every method of a path
+// still shares the same two candidates and Methods scans both.
+func WithTwoChoice() Option {
+	return func(cfg *config) {
+		cfg.twoChoice = true
+	}
+}
+
+// altGroup returns the alternate candidate set of a key whose home set is
+// group and whose path signature is psig. The offset is XORed in, so the
+// alternate of the alternate is the home set again. It equals group only in a
+// cache with a single set.
+func (c *LRUCache) altGroup(group uint32, psig uint8) uint32 {
+	mask := c.numSets - 1
+	off := (uint32(psig) * 0x9E3779B1 >> 7) & mask
+	if off == 0 {
+		off = 1 & mask
+	}
+	return group ^ off
+}
+
+// probe looks a route up in its home set and, in two-choice mode, its
+// alternate set, returning lookup's results.
+func (c *LRUCache) probe(group uint32, sig uint16, psig uint8, method, path string, extra *[MaxKeyExtra]string, ns uint32) (chk *chunk, idx, bit, seq uint32, ok bool) {
+	if chk, idx, bit, seq, ok = c.lookup(group, sig, method, path, extra, ns); ok || !c.twoChoice {
+		return chk, idx, bit, seq, ok
+	}
+	if alt := c.altGroup(group, psig); alt != group {
+		return c.lookup(alt, sig, method, path, extra, ns)
+	}
+	return chk, idx, bit, seq, ok
+}
+
+// place picks the set a new entry goes into. Outside two-choice mode that is
+// always the home set; otherwise it is the candidate with more free slots,
+// or, when both are full, the one with an unreferenced victim. Ties go to the
+// home set so that hits keep costing a single scan whenever possible.
+func (c *LRUCache) place(group uint32, psig uint8) uint32 {
+	if !c.twoChoice {
+		return group
+	}
+	alt := c.altGroup(group, psig)
+	if alt == group {
+		return group
+	}
+	hf, hcold := c.setLoad(group)
+	of, ocold := c.setLoad(alt)
+	switch {
+	case of > hf:
+		return alt
+	case of < hf || hf > 0:
+		return group
+	case !hcold && ocold:
+		return alt
+	}
+	return group
+}
+
+// setLoad reconciles the chunks of set group and returns its number of free
+// slots and whether it holds a valid slot whose reference bit is clear.
+func (c *LRUCache) setLoad(group uint32) (free int, cold bool) {
+	first, n, mask := c.usable(group)
+	gen := c.gen.Load()
+	for k := first; k < first+n; k++ {
+		chk := &c.chunks[k]
+		if chk.gen.Load() != gen {
+			c.reconcile(chk, k, gen)
+		}
+		v, w := chk.valid.Load()&mask, chk.writing.Load()&mask
+		free += bits.OnesCount64(^v & ^w & mask)
+		cold = cold || v&^chk.accessed.Load()&^w != 0
+	}
+	return free, cold
+}
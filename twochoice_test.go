@@ -0,0 +1,110 @@
+package liteLRU
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// collidingPaths returns n paths whose home set is group 0 in c.
+func collidingPaths(c *LRUCache, n int) []string {
+	var out []string
+	for i := 0; len(out) < n; i++ {
+		path := fmt.Sprintf("/c/%d", i)
+		if g, _, _ := c.locatePath(path, nil, 0); g == 0 {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func TestTwoChoiceAltGroup(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithTwoChoice())
+	defer c.Close()
+
+	for g := uint32(0); g < c.numSets; g++ {
+		for psig := 1; psig < 256; psig++ {
+			alt := c.altGroup(g, uint8(psig))
+			if alt == g || alt >= c.numSets {
+				t.Fatalf("altGroup(%d, %d) = %d", g, psig, alt)
+			}
+			if back := c.altGroup(alt, uint8(psig)); back != g {
+				t.Fatalf("alternate of %d is %d, want %d", alt, back, g)
+			}
+		}
+	}
+}
+
+func TestTwoChoiceConflictMisses(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"one-choice", nil},
+		{"two-choice", []Option{WithTwoChoice()}},
+		{"two-choice-arena", []Option{WithTwoChoice(), WithArena(0)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewLRUCache(1024, 10, tc.opts...)
+			defer c.Close()
+
+			paths := collidingPaths(c, 96)
+			for _, p := range paths {
+				c.Add("GET", p, nil, []Param{{Key: "p", Value: p}})
+			}
+			hits := 0
+			for _, p := range paths {
+				if _, params, ok := c.Get("GET", p, nil); ok {
+					if params[0].Value != p {
+						t.Fatalf("%s returned params of %s", p, params[0].Value)
+					}
+					hits++
+				}
+			}
+			if c.twoChoice && hits != len(paths) {
+				t.Fatalf("two-choice kept %d/%d colliding paths", hits, len(paths))
+			}
+			if !c.twoChoice && hits > 64 {
+				t.Fatalf("one set kept %d entries", hits)
+			}
+		})
+	}
+}
+
+func TestTwoChoiceOperations(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithTwoChoice(), WithValues())
+	defer c.Close()
+
+	paths := collidingPaths(c, 96)
+	for _, p := range paths {
+		c.Add("GET", p, nil, nil)
+		c.Add("POST", p, nil, nil)
+	}
+	// Both methods of a path must land in its two candidate sets.
+	for _, p := range paths {
+		if got := c.Methods(p); len(got) != 2 || !slices.Contains(got, "GET") || !slices.Contains(got, "POST") {
+			t.Fatalf("Methods(%s) = %v", p, got)
+		}
+	}
+	last := paths[len(paths)-1]
+	if m, _, _, ok := c.GetAnyMethod(last, nil); !ok || (m != "GET" && m != "POST") {
+		t.Fatalf("GetAnyMethod = %q %v", m, ok)
+	}
+	c.Add("GET", last, nil, []Param{{Key: "v", Value: "2"}})
+	if _, p, ok := c.Peek("GET", last, nil); !ok || len(p) != 1 || p[0].Value != "2" {
+		t.Fatalf("update in alternate set: %v %+v", ok, p)
+	}
+	if !c.View("GET", last, func(HandlerFunc, []Param) {}) {
+		t.Fatal("View missed an entry in its alternate set")
+	}
+	if !c.Remove("GET", last) || c.Remove("GET", last) {
+		t.Fatal("Remove of an entry in its alternate set")
+	}
+	if _, _, ok := c.Get("GET", last, nil); ok {
+		t.Fatal("removed entry still served")
+	}
+	c.AddValue("VAL", last, 7)
+	if v, ok := c.GetValue("VAL", last); !ok || v != 7 {
+		t.Fatalf("GetValue = %v %v", v, ok)
+	}
+}
@@ -0,0 +1,163 @@
+package liteLRU
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// WithValues gives every slot a GC-visible pointer to an arbitrary value, set
+// with AddValue and read with GetValue and PeekValue. It costs 8 bytes of heap
+// per slot plus one allocation per AddValue. Without it AddValue only counts
+// drops.
+func WithValues() Option {
+	return func(cfg *config) {
+		cfg.values = true
+	}
+}
+
+// AddValue adds or updates the entry for method and path with an arbitrary
+// value instead of a handler and params, and reports whether a valid entry
+// was evicted to make room for it.
+func (c *LRUCache) AddValue(method, path string, value any) (evicted bool) {
+	if c.values == nil {
+		_, stripeIdx, _, _ := c.locate(method, path, nil, 0)
+		c.drop(stripeIdx, nil)
+		return false
+	}
+	return c.add(method, path, nil, nil, &entryMeta{value: &value})
+}
+
+// GetValue returns the value stored for method and path by AddValue, marking
+// the entry as recently used and counting a hit or miss like Get.
+func (c *LRUCache) GetValue(method, path string) (any, bool) {
+	return c.value(method, path, true)
+}
+
+// PeekValue is like GetValue but neither marks the entry as recently used nor
+// updates statistics.
+func (c *LRUCache) PeekValue(method, path string) (any, bool) {
+	return c.value(method, path, false)
+}
+
+// value implements GetValue and PeekValue; touch selects the Get behaviour.
+func (c *LRUCache) value(method, path string, touch bool) (any, bool) {
+	method, path = c.normalizeKey(method, path)
+	group, stripeIdx, sig, psig := c.locate(method, path, nil, 0)
+
+	if c.values != nil {
+		if chk, idx, bit, seq1, ok := c.probe(group, sig, psig, method, path, nil, 0); ok {
+			v := c.values[idx].Load()
+			if c.state(idx).seq.Load() == seq1 && c.state(idx).status.Load() == 0 {
+				if touch {
+					markAccessed(chk, bit)
+					c.stats.at(stripeIdx).hits.Add(1)
+				}
+				if v == nil {
+					return nil, true
+				}
+				return *v, true
+			}
+		}
+	}
+	if touch {
+		c.stats.at(stripeIdx).misses.Add(1)
+	}
+	return nil, false
+}
+
+// Peek is like Get but neither marks the entry as recently used nor updates
+// statistics, so it does not protect the entry from eviction.
+func (c *LRUCache) Peek(method, path string, dst []Param) (HandlerFunc, []Param, bool) {
+	method, path = c.normalizeKey(method, path)
+	group, _, sig, psig := c.locate(method, path, nil, 0)
+
+	if _, idx, _, seq1, ok := c.probe(group, sig, psig, method, path, nil, 0); ok {
+		handler := c.handler(idx)
+		params, ok := c.loadParams(idx, dst)
+		if ok && c.state(idx).seq.Load() == seq1 && c.state(idx).status.Load() == 0 {
+			return handler, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+// Remove evicts the entry for method and path, including a negative entry,
+// and reports whether one was present.
+func (c *LRUCache) Remove(method, path string) bool {
+	method, path = c.normalizeKey(method, path)
+	group, _, sig, psig := c.locate(method, path, nil, 0)
+
+	parked := c.victims != nil && c.victims.forget(uint8(sig), method, path, nil, 0, c.gen.Load())
+	chk, idx, bit, _, ok := c.probe(group, sig, psig, method, path, nil, 0)
+	if !ok || !claimSlot(chk, bit) {
+		return parked
+	}
+	// Re-check under the writing bit: the slot may have been reused.
+	removed := chk.valid.Load()&(1<<bit) != 0 && c.state(idx).gen.Load() == c.gen.Load() &&
+		c.state(idx).ns.Load() == 0 && c.keyEquals(idx, method, path, nil)
+	if removed {
+		c.clearSlot(chk, idx, bit)
+	}
+	releaseSlot(chk, bit)
+	return removed || parked
+}
+
+// Len returns the number of entries in the cache, including negative,
+// namespaced and composite-key entries. It counts valid bits chunk by chunk,
+// so it costs one pass over the chunk array and is only a snapshot under
+// concurrent writes.
+func (c *LRUCache) Len() int {
+	gen := c.gen.Load()
+	n := 0
+	for group := range c.chunks {
+		chk := &c.chunks[group]
+		// A chunk not reconciled since the last InvalidateAll holds no current
+		// entries: every insert reconciles its chunk first.
+		if chk.gen.Load() == gen {
+			n += bits.OnesCount64(chk.valid.Load())
+		}
+	}
+	return n
+}
+
+// Range calls fn for each entry of the shared key space that has no extra key
+// parts and is not negative, with its method, path and the value stored by
+// AddValue (nil for entries added otherwise), until fn returns false. The
+// order is unspecified. Entries added or removed while Range runs may or may
+// not be visited.
+func (c *LRUCache) Range(fn func(method, path string, value any) bool) {
+	gen := c.gen.Load()
+	for group := uint32(0); group < c.numGroups; group++ {
+		chk := &c.chunks[group]
+		if chk.gen.Load() != gen {
+			continue
+		}
+		for m := chk.valid.Load(); m != 0; m &= m - 1 {
+			idx := group*64 + uint32(bits.TrailingZeros64(m))
+			st := c.state(idx)
+			seq := st.seq.Load()
+			if seq%2 != 0 || st.gen.Load() != gen || st.ns.Load() != 0 || st.status.Load() != 0 {
+				continue
+			}
+			method, path, ok := c.slotKey(idx)
+			if !ok || !c.keyEquals(idx, method, path, nil) {
+				continue
+			}
+			if c.arena != nil {
+				method, path = strings.Clone(method), strings.Clone(path)
+			}
+			var value any
+			if c.values != nil {
+				if v := c.values[idx].Load(); v != nil {
+					value = *v
+				}
+			}
+			if st.seq.Load() != seq {
+				continue
+			}
+			if !fn(method, path, value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	for _, opts := range [][]Option{{WithValues()}, {WithValues(), WithArena(0)}} {
+		c := NewLRUCache(256, 10, opts...)
+
+		if c.AddValue("GET", "/a", 1) {
+			t.Fatal("AddValue into an empty cache evicted")
+		}
+		c.AddValue("GET", "/b", "two")
+		c.Add("GET", "/c", nil, nil)
+
+		if v, ok := c.GetValue("GET", "/a"); !ok || v != 1 {
+			t.Fatalf("GetValue = %v %v", v, ok)
+		}
+		if v, ok := c.PeekValue("GET", "/b"); !ok || v != "two" {
+			t.Fatalf("PeekValue = %v %v", v, ok)
+		}
+		if v, ok := c.GetValue("GET", "/c"); !ok || v != nil {
+			t.Fatalf("value of a plain entry = %v %v", v, ok)
+		}
+
+		seen := map[string]any{}
+		c.Range(func(method, path string, value any) bool {
+			seen[method+" "+path] = value
+			return true
+		})
+		if len(seen) != 3 || seen["GET /a"] != 1 || seen["GET /b"] != "two" {
+			t.Fatalf("Range saw %v", seen)
+		}
+		c.Close()
+	}
+
+	plain := NewLRUCache(64, 10)
+	defer plain.Close()
+	plain.AddValue("GET", "/a", 1)
+	if _, ok := plain.GetValue("GET", "/a"); ok {
+		t.Fatal("AddValue without WithValues stored a value")
+	}
+	if _, _, drops, _ := plain.Stats(); drops != 1 {
+		t.Fatalf("drops = %d", drops)
+	}
+}
+
+func TestPeekRemoveLen(t *testing.T) {
+	c := NewLRUCache(256, 10)
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		c.Add("GET", fmt.Sprintf("/%d", i), nil, []Param{{Key: "i", Value: fmt.Sprint(i)}})
+	}
+	if c.Len() != 100 {
+		t.Fatalf("Len = %d", c.Len())
+	}
+
+	hits, _, _, _ := c.Stats()
+	if _, p, ok := c.Peek("GET", "/7", nil); !ok || p[0].Value != "7" {
+		t.Fatalf("Peek = %v %+v", ok, p)
+	}
+	if h, _, _, _ := c.Stats(); h != hits {
+		t.Fatal("Peek counted a hit")
+	}
+
+	if !c.Remove("GET", "/7") || c.Remove("GET", "/7") {
+		t.Fatal("Remove should report presence once")
+	}
+	if _, _, ok := c.Get("GET", "/7", nil); ok || c.Len() != 99 {
+		t.Fatalf("after Remove: found=%v Len=%d", ok, c.Len())
+	}
+
+	c.InvalidateAll()
+	if c.Len() != 0 {
+		t.Fatalf("Len after InvalidateAll = %d", c.Len())
+	}
+	c.Add("GET", "/new", nil, nil)
+	if c.Len() != 1 {
+		t.Fatalf("Len = %d", c.Len())
+	}
+}
@@ -0,0 +1,225 @@
+package liteLRU
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// WithVictimBuffer puts a small fully associative buffer of size slots behind
+// the sets. Valid entries evicted by CLOCK are copied into it instead of being
+// lost, and a Get (or Lookup, GetKey) that misses its set probes the buffer
+// and promotes a hit back into the set. This recovers conflict misses of a
+// thrashing set without changing set geometry. Values <= 0 select 256 slots;
+// sizes are rounded up to a multiple of 8.
+//
+// Each eviction then costs one heap allocation for the parked copy, and each
+// insert scans the buffer's signatures to drop a stale copy of its key.
+// Negative entries and entries evicted to enforce a namespace quota are not
+// parked. Len, Range and Methods do not see parked entries.
+func WithVictimBuffer(size int) Option {
+	return func(cfg *config) {
+		if size <= 0 {
+			size = 256
+		}
+		cfg.victims = (size + 7) &^ 7
+	}
+}
+
+// victim is an entry parked in the victim buffer. It owns copies of all its
+// strings and params and is never modified once published.
+type victim struct {
+	method, path string
+	extra        [MaxKeyExtra]string
+	handler      HandlerFunc
+	params       []Param
+	value        *any
+	tags         [MaxTags]uint32
+	ns           uint32
+	gen          uint32
+	deadline     int64
+}
+
+// victimBuffer is a lock-free ring of parked entries. Writers claim the next
+// slot by bumping the cursor and overwrite whatever it held; the 8-bit route
+// signature of each slot sits in a SWAR plane so a probe scans eight slots
+// per word, as lookup does for a set. Probes confirm a candidate by comparing
+// the full key of the record they loaded, so a signature that is briefly out
+// of step with its slot only costs a comparison.
+type victimBuffer struct {
+	slots  []atomic.Pointer[victim]
+	sigs   []atomic.Uint64
+	cursor atomic.Uint64
+
+	captured atomic.Int64
+	hits     atomic.Int64
+}
+
+// newVictimBuffer returns a buffer of size slots, or nil if size is 0.
+func newVictimBuffer(size int) *victimBuffer {
+	if size == 0 {
+		return nil
+	}
+	return &victimBuffer{
+		slots: make([]atomic.Pointer[victim], size),
+		sigs:  make([]atomic.Uint64, size/8),
+	}
+}
+
+// put parks v under route signature sig, displacing the oldest record.
+func (b *victimBuffer) put(v *victim, sig uint8) {
+	pos := uint32((b.cursor.Add(1) - 1) % uint64(len(b.slots)))
+	b.slots[pos].Store(v)
+	setSig(b.sigs, pos, sig)
+	b.captured.Add(1)
+}
+
+// find returns the slot and record of a current-generation entry for the
+// given key, or -1 and nil.
+func (b *victimBuffer) find(sig uint8, method, path string, extra *[MaxKeyExtra]string, ns, gen uint32) (int, *victim) {
+	var parts [MaxKeyExtra]string
+	if extra != nil {
+		parts = *extra
+	}
+	for i := range b.sigs {
+		word := b.sigs[i].Load()
+		if !hasByteSWAR(word, sig) {
+			continue
+		}
+		for j := 0; j < 8; j++ {
+			if byte(word>>(j*8)) != sig {
+				continue
+			}
+			pos := i*8 + j
+			v := b.slots[pos].Load()
+			if v != nil && v.gen == gen && v.ns == ns &&
+				v.method == method && v.path == path && v.extra == parts {
+				return pos, v
+			}
+		}
+	}
+	return -1, nil
+}
+
+// take unlinks and returns the record of a key, or nil. Of two concurrent
+// takers of one record only one gets it.
+func (b *victimBuffer) take(sig uint8, method, path string, extra *[MaxKeyExtra]string, ns, gen uint32) *victim {
+	for {
+		pos, v := b.find(sig, method, path, extra, ns, gen)
+		if v == nil {
+			return nil
+		}
+		if b.slots[pos].CompareAndSwap(v, nil) {
+			return v
+		}
+	}
+}
+
+// forget drops every record of a key and reports whether there was one.
+func (b *victimBuffer) forget(sig uint8, method, path string, extra *[MaxKeyExtra]string, ns, gen uint32) bool {
+	found := false
+	for b.take(sig, method, path, extra, ns, gen) != nil {
+		found = true
+	}
+	return found
+}
+
+// invalidateTag drops every record carrying the tag hash h.
+func (b *victimBuffer) invalidateTag(h uint32) {
+	for i := range b.slots {
+		v := b.slots[i].Load()
+		if v != nil && slices.Contains(v.tags[:], h) {
+			b.slots[i].CompareAndSwap(v, nil)
+		}
+	}
+}
+
+// clear drops every record.
+func (b *victimBuffer) clear() {
+	for i := range b.slots {
+		b.slots[i].Store(nil)
+	}
+}
+
+// capture copies the entry of slot idx, which the caller is about to evict,
+// into a victim record. The caller owns the slot's writing bit, so the slot
+// cannot change underneath. It returns nil for entries that are not worth
+// parking: negative entries, entries from an older generation, and records
+// that fail to decode.
+func (c *LRUCache) capture(idx uint32) *victim {
+	st := c.state(idx)
+	gen := c.gen.Load()
+	if st.status.Load() != 0 || st.gen.Load() != gen {
+		return nil
+	}
+	v := &victim{
+		handler:  c.handler(idx),
+		ns:       st.ns.Load(),
+		gen:      gen,
+		deadline: st.deadline.Load() &^ 1, // drop a refresh claim
+	}
+	for i := range st.tags {
+		v.tags[i] = st.tags[i].Load()
+	}
+	if c.values != nil {
+		v.value = c.values[idx].Load()
+	}
+
+	if c.methods != nil {
+		v.method = c.methods[idx].Load()
+		v.path = c.paths[idx].Load()
+		for i := range c.extras {
+			v.extra[i] = c.extras[i][idx].Load()
+		}
+		// The slot's params array is recycled in place by the next write.
+		v.params = slices.Clone(c.params[idx].Load())
+		return v
+	}
+
+	b := c.record(idx)
+	if b == nil {
+		return nil
+	}
+	method, path, ok := recordKey(b)
+	if !ok || !recordExtra(b, &v.extra) {
+		return nil
+	}
+	params, ok := recordParams(b, nil)
+	if !ok {
+		return nil
+	}
+	if c.arena == nil {
+		// Compact records are immutable; the victim can keep aliasing one.
+		v.method, v.path, v.params = method, path, params
+		return v
+	}
+	v.method = strings.Clone(method)
+	v.path = strings.Clone(path)
+	for i := range v.extra {
+		v.extra[i] = strings.Clone(v.extra[i])
+	}
+	for i := range params {
+		params[i] = Param{Key: strings.Clone(params[i].Key), Value: strings.Clone(params[i].Value)}
+	}
+	v.params = params
+	return v
+}
+
+// promote moves a record taken from the victim buffer back into its set.
+func (c *LRUCache) promote(v *victim) {
+	meta := &entryMeta{tags: v.tags, ns: v.ns, value: v.value, deadline: v.deadline}
+	if v.extra != ([MaxKeyExtra]string{}) {
+		meta.extra = &v.extra
+	}
+	c.add(v.method, v.path, v.handler, v.params, meta)
+}
+
+// VictimStats returns how many evicted entries were parked in the victim
+// buffer and how many lookups were served from it. Both are zero without
+// WithVictimBuffer.
+func (c *LRUCache) VictimStats() (captured, hits int64) {
+	if c.victims == nil {
+		return 0, 0
+	}
+	return c.victims.captured.Load(), c.victims.hits.Load()
+}
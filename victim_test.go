@@ -0,0 +1,142 @@
+package liteLRU
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestVictimBufferRecoversConflictMisses(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"heap", []Option{WithVictimBuffer(0)}},
+		{"arena", []Option{WithVictimBuffer(0), WithArena(0)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewLRUCache(1024, 10, tc.opts...)
+			defer c.Close()
+
+			paths := collidingPaths(c, 96)
+			for _, p := range paths {
+				c.Add("GET", p, nil, []Param{{Key: "p", Value: p}})
+			}
+			if captured, _ := c.VictimStats(); captured != 32 {
+				t.Fatalf("parked %d entries, want 32", captured)
+			}
+			// Every lookup either hits its set or promotes from the buffer,
+			// which parks another entry in exchange.
+			for round := 0; round < 2; round++ {
+				for _, p := range paths {
+					_, params, ok := c.Get("GET", p, nil)
+					if !ok {
+						t.Fatalf("round %d: %s missed", round, p)
+					}
+					if len(params) != 1 || params[0].Value != p {
+						t.Fatalf("%s returned %+v", p, params)
+					}
+				}
+			}
+			if _, hits := c.VictimStats(); hits == 0 {
+				t.Fatal("no lookups were served from the victim buffer")
+			}
+			if c.Len() != 64 {
+				t.Fatalf("Len = %d, parked entries must not count", c.Len())
+			}
+		})
+	}
+}
+
+// parkedPath fills a set until its first path is parked in the victim buffer
+// and returns that path.
+func parkedPath(t *testing.T, c *LRUCache, tags ...string) string {
+	t.Helper()
+	paths := collidingPaths(c, 65)
+	for _, p := range paths {
+		c.AddTagged("GET", p, nil, []Param{{Key: "v", Value: "1"}}, tags...)
+	}
+	if _, _, ok := c.Peek("GET", paths[0], nil); ok {
+		t.Fatal("expected the first path to be evicted from its set")
+	}
+	return paths[0]
+}
+
+func TestVictimBufferInvalidation(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithVictimBuffer(64))
+	defer c.Close()
+
+	p := parkedPath(t, c)
+	if !c.Remove("GET", p) {
+		t.Fatal("Remove of a parked entry reported nothing removed")
+	}
+	if _, _, ok := c.Get("GET", p, nil); ok {
+		t.Fatal("removed parked entry was served")
+	}
+
+	c.Clear()
+	p = parkedPath(t, c, "grp")
+	c.InvalidateTag("grp")
+	if _, _, ok := c.Get("GET", p, nil); ok {
+		t.Fatal("tag-invalidated parked entry was served")
+	}
+
+	c.Clear()
+	p = parkedPath(t, c)
+	c.InvalidateAll()
+	if _, _, ok := c.Get("GET", p, nil); ok {
+		t.Fatal("parked entry survived InvalidateAll")
+	}
+
+	p = parkedPath(t, c)
+	c.Clear()
+	if _, _, ok := c.Get("GET", p, nil); ok {
+		t.Fatal("parked entry survived Clear")
+	}
+}
+
+func TestVictimBufferDropsStaleCopies(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithVictimBuffer(64))
+	defer c.Close()
+
+	p := parkedPath(t, c)
+	c.Add("GET", p, nil, []Param{{Key: "v", Value: "2"}})
+	// Push the new version out again; only it may come back.
+	for _, q := range collidingPaths(c, 200)[65:] {
+		c.Add("GET", q, nil, nil)
+		if _, _, ok := c.Peek("GET", p, nil); !ok {
+			break
+		}
+	}
+	if _, params, ok := c.Get("GET", p, nil); !ok || params[0].Value != "2" {
+		t.Fatalf("Get = %v %+v, want version 2", ok, params)
+	}
+}
+
+func TestVictimBufferConcurrent(t *testing.T) {
+	// Arena mode: heap mode recycles params arrays in place under the
+	// seqlock, which the race detector reports for any concurrent rewrite.
+	c := NewLRUCache(1024, 10, WithVictimBuffer(0), WithArena(0))
+	defer c.Close()
+
+	paths := collidingPaths(c, 128)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			dst := make([]Param, 0, 1)
+			for i := 0; i < 5000; i++ {
+				p := paths[(i*7+w)%len(paths)]
+				if _, params, ok := c.Get("GET", p, dst); ok {
+					if len(params) != 1 || params[0].Value != p {
+						t.Errorf("%s returned %+v", p, params)
+						return
+					}
+				} else {
+					c.Add("GET", p, nil, []Param{{Key: "p", Value: p}})
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,75 @@
+package liteLRU
+
+import "sync"
+
+// viewParams recycles decode buffers for View in arena and compact mode,
+// where params have to be materialised from record bytes before they can be
+// lent out.
+var viewParams = sync.Pool{
+	New: func() any {
+		buf := make([]Param, 0, 16)
+		return &buf
+	},
+}
+
+// View looks up an entry and, on a hit, calls fn with the stored handler and
+// params in place, without copying them into a caller buffer. While fn runs
+// the entry's memory is pinned: a concurrent Add allocates a fresh params
+// array instead of overwriting the borrowed one, and in arena mode evicted
+// records are not recycled until every borrower that could see them has
+// returned. fn must treat params as read-only and must not retain the slice
+// or, in arena mode, its strings after it returns.
+//
+// View reports whether the entry was found and fn was called.
+func (c *LRUCache) View(method, path string, fn func(h HandlerFunc, params []Param)) bool {
+	method, path = c.normalizeKey(method, path)
+	group, stripeIdx, sig, psig := c.locate(method, path, nil, 0)
+
+	if chk, idx, bit, seq1, ok := c.probe(group, sig, psig, method, path, nil, 0); ok {
+		// Pin the slot before reading it. A writer that began rewriting it
+		// before the pin fails the seqlock check below; every later one sees
+		// the pin and leaves the slot's memory alone until fn returns.
+		e := c.epoch.enter(idx)
+		defer c.epoch.exit(idx, e)
+
+		handler := c.handler(idx)
+
+		var (
+			params []Param
+			buf    *[]Param
+		)
+		if c.methods == nil {
+			buf = viewParams.Get().(*[]Param)
+			if b := c.record(idx); b != nil {
+				params, ok = recordParams(b, (*buf)[:0])
+			} else {
+				ok = false
+			}
+		} else {
+			params = c.params[idx].Load()
+		}
+		deadline := c.state(idx).deadline.Load()
+		status := c.state(idx).status.Load()
+
+		// Validate read seqlock; past this point the pin keeps params stable.
+		// Negative entries have nothing to lend and read as misses here.
+		if ok && c.state(idx).seq.Load() == seq1 && status == 0 {
+			markAccessed(chk, bit)
+			c.stats.at(stripeIdx).hits.Add(1)
+			if deadline != 0 {
+				c.loader.check(c, idx, seq1, deadline, method, path, nil)
+			}
+			fn(handler, params)
+			if buf != nil {
+				viewParams.Put(buf)
+			}
+			return true
+		}
+		if buf != nil {
+			viewParams.Put(buf)
+		}
+	}
+
+	c.stats.at(stripeIdx).misses.Add(1)
+	return false
+}
@@ -0,0 +1,166 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestViewHitMiss(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithArena(0)}} {
+		c := NewLRUCache(128, 10, opts...)
+
+		c.Add("GET", "/items/7", nil, []Param{{Key: "id", Value: "7"}})
+
+		var got []Param
+		if !c.View("GET", "/items/7", func(h HandlerFunc, params []Param) {
+			got = append(got, params...)
+		}) {
+			t.Fatal("expected View hit")
+		}
+		if len(got) != 1 || got[0] != (Param{"id", "7"}) {
+			t.Fatalf("unexpected params %+v", got)
+		}
+		if c.View("GET", "/items/8", func(HandlerFunc, []Param) { t.Fatal("fn called on miss") }) {
+			t.Fatal("expected View miss")
+		}
+		if hits, misses, _, _ := c.Stats(); hits != 1 || misses != 1 {
+			t.Fatalf("stats hits=%d misses=%d", hits, misses)
+		}
+		c.Close()
+	}
+}
+
+func TestViewBorrowSurvivesUpdate(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithArena(0)}} {
+		c := NewLRUCache(64, 10, opts...)
+
+		c.Add("GET", "/borrowed", nil, []Param{{Key: "v", Value: "old"}})
+
+		c.View("GET", "/borrowed", func(_ HandlerFunc, params []Param) {
+			// Rewrite the entry and churn the arena while the borrow is open.
+			c.Add("GET", "/borrowed", nil, []Param{{Key: "v", Value: "new"}})
+			for i := 0; i < 5000; i++ {
+				c.Add("GET", fmt.Sprintf("/churn/%d", i), nil, []Param{{Key: "v", Value: "xxx"}})
+			}
+			if params[0].Value != "old" {
+				t.Fatalf("borrowed params changed underneath View: %+v", params)
+			}
+		})
+
+		_, params, ok := c.Get("GET", "/borrowed", nil)
+		if ok && params[0].Value != "new" {
+			t.Fatalf("update lost: %+v", params)
+		}
+		c.Close()
+	}
+}
+
+func TestViewArenaReclaimsAfterBorrowers(t *testing.T) {
+	c := NewLRUCache(64, 10, WithArena(1))
+	defer c.Close()
+
+	c.Add("GET", "/pin", nil, nil)
+	c.View("GET", "/pin", func(HandlerFunc, []Param) {})
+
+	// With the epoch domain active and no borrower pinned, retired blocks
+	// must flow back to the free lists instead of exhausting the arena.
+	for i := 0; i < 100000; i++ {
+		c.Add("GET", fmt.Sprintf("/churn/%d", i), nil, []Param{{Key: "i", Value: fmt.Sprint(i)}})
+	}
+	if _, _, drops, _ := c.Stats(); drops != 0 {
+		t.Fatalf("retired blocks never reclaimed: %d drops", drops)
+	}
+}
+
+func TestViewDrainsLimboAfterBorrowers(t *testing.T) {
+	c := NewLRUCache(64, 10, WithArena(0))
+	defer c.Close()
+
+	c.Add("GET", "/pin", nil, nil)
+	c.View("GET", "/pin", func(HandlerFunc, []Param) {
+		// Rewriting the borrowed entry must park its old record.
+		c.Add("GET", "/pin", nil, []Param{{Key: "v", Value: "new"}})
+		for i := 0; i < 100; i++ {
+			c.Add("GET", fmt.Sprintf("/churn/%d", i), nil, nil)
+		}
+		if !c.epoch.pinned() {
+			t.Fatal("borrower not counted while pinned")
+		}
+	})
+	if c.epoch.pinned() {
+		t.Fatal("borrower still counted after View returned")
+	}
+	if c.arena.parked.Load() == 0 {
+		t.Fatal("blocks evicted under a borrower were not parked")
+	}
+	for i := 0; i < 3; i++ {
+		c.Add("GET", fmt.Sprintf("/after/%d", i), nil, nil)
+	}
+	if n := c.arena.parked.Load(); n != 0 {
+		t.Fatalf("%d blocks still parked after the borrower left", n)
+	}
+}
+
+func BenchmarkAddAfterView(b *testing.B) {
+	c := NewLRUCache(1024, 10, WithArena(0))
+	defer c.Close()
+	c.Add("GET", "/pin", nil, nil)
+	c.View("GET", "/pin", func(HandlerFunc, []Param) {})
+	paths := make([]string, 4096)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/bench/%d", i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Add("GET", paths[i&4095], nil, nil)
+	}
+}
+
+func BenchmarkViewVsGetParallel(b *testing.B) {
+	for _, n := range []int{1, 8} {
+		c := NewLRUCache(4096, 8)
+		params := make([]Param, n)
+		for i := range params {
+			params[i] = Param{Key: fmt.Sprintf("k%d", i), Value: fmt.Sprintf("value-%d", i)}
+		}
+		paths := make([]string, 1024)
+		for i := range paths {
+			paths[i] = fmt.Sprintf("/bench/%d", i)
+			c.Add("GET", paths[i], nil, params)
+		}
+
+		b.Run(fmt.Sprintf("Get/params=%d", n), func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				var buf [8]Param
+				i := 0
+				for pb.Next() {
+					c.Get("GET", paths[i&1023], buf[:0])
+					i++
+				}
+			})
+		})
+		b.Run(fmt.Sprintf("View/params=%d", n), func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				var sink int
+				fn := func(_ HandlerFunc, params []Param) { sink += len(params) }
+				i := 0
+				for pb.Next() {
+					c.View("GET", paths[i&1023], fn)
+					i++
+				}
+				_ = sink
+			})
+		})
+		c.Close()
+	}
+}
+
+// pinned reports whether any borrower is pinned on any stripe.
+func (d *epochDomain) pinned() bool {
+	for i := uint32(0); i < 64; i++ {
+		if !d.quiescent(i) {
+			return true
+		}
+	}
+	return false
+}
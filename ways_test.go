@@ -0,0 +1,115 @@
+package liteLRU
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWaysGeometry(t *testing.T) {
+	for _, tc := range []struct{ in, want int }{{0, 64}, {8, 16}, {24, 32}, {64, 64}, {100, 128}, {512, 128}} {
+		c := NewLRUCache(64, 10, WithWays(tc.in))
+		if int(c.ways) != tc.want {
+			t.Errorf("WithWays(%d) gave %d ways", tc.in, c.ways)
+		}
+		if int(c.numSets*c.ways) != int(c.capacity) {
+			t.Errorf("%d ways: %d sets for capacity %d", c.ways, c.numSets, c.capacity)
+		}
+		c.Close()
+	}
+}
+
+func TestWaysSetBounds(t *testing.T) {
+	for _, ways := range []int{16, 32, 64, 128} {
+		t.Run(fmt.Sprintf("%d-way", ways), func(t *testing.T) {
+			c := NewLRUCache(1024, 10, WithWays(ways))
+			defer c.Close()
+
+			// More colliding paths than the set holds: exactly ways survive,
+			// and no neighbouring set is touched.
+			paths := collidingPaths(c, ways+ways/2)
+			for _, p := range paths {
+				c.Add("GET", p, nil, []Param{{Key: "p", Value: p}})
+			}
+			hits := 0
+			for _, p := range paths {
+				if _, params, ok := c.Peek("GET", p, nil); ok {
+					if params[0].Value != p {
+						t.Fatalf("%s returned params of %s", p, params[0].Value)
+					}
+					hits++
+				}
+			}
+			if hits != ways || c.Len() != ways {
+				t.Fatalf("set kept %d entries (Len %d), want %d", hits, c.Len(), ways)
+			}
+
+			// Lookups, Methods and Remove see entries anywhere in the set.
+			last := paths[len(paths)-1]
+			c.Add("POST", last, nil, nil)
+			if m := c.Methods(last); len(m) != 2 {
+				t.Fatalf("Methods = %v", m)
+			}
+			if !c.Remove("POST", last) {
+				t.Fatal("Remove missed an entry")
+			}
+		})
+	}
+}
+
+func TestWaysFillCapacity(t *testing.T) {
+	for _, ways := range []int{16, 128} {
+		c := NewLRUCache(4096, 10, WithWays(ways))
+		for i := 0; i < 4096; i++ {
+			c.Add("GET", fmt.Sprintf("/fill/%d", i), nil, nil)
+		}
+		if n := c.Len(); n < 3000 {
+			t.Errorf("%d ways: only %d of 4096 slots used", ways, n)
+		}
+		c.Close()
+	}
+}
+
+func TestWaysNamespaceQuota(t *testing.T) {
+	c := NewLRUCache(1024, 10, WithWays(16))
+	defer c.Close()
+
+	ns := c.Namespace("t", 50)
+	for i := 0; i < 2000; i++ {
+		ns.Add("GET", fmt.Sprintf("/%d", i), nil, nil)
+	}
+	if n := ns.Len(); n > 50 {
+		t.Fatalf("namespace holds %d entries, quota 50", n)
+	}
+}
+
+// BenchmarkWays compares associativities on a skewed mixed workload whose
+// working set is twice the capacity, reporting the hit ratio alongside
+// ns/op.
+func BenchmarkWays(b *testing.B) {
+	paths := make([]string, 8192)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/api/resource/%d", i)
+	}
+	for _, ways := range []int{16, 32, 64, 128} {
+		b.Run(fmt.Sprintf("%d-way", ways), func(b *testing.B) {
+			cache := NewLRUCache(4096, 10, WithWays(ways))
+			defer cache.Close()
+			b.RunParallel(func(pb *testing.PB) {
+				seed := uint32(1)
+				for pb.Next() {
+					seed ^= seed << 13
+					seed ^= seed >> 17
+					seed ^= seed << 5
+					// Square the draw to skew towards low indices.
+					r := seed % uint32(len(paths))
+					path := paths[r*r/uint32(len(paths))]
+					if _, _, ok := cache.Get("GET", path, nil); !ok {
+						cache.Add("GET", path, nil, nil)
+					}
+				}
+			})
+			_, _, _, ratio := cache.Stats()
+			b.ReportMetric(ratio*100, "hit%")
+		})
+	}
+}